@@ -26,13 +26,18 @@ import (
 	"github.com/mattmoor/korpc/pkg/generate"
 	"github.com/mattmoor/korpc/pkg/install"
 	"github.com/mattmoor/korpc/pkg/protoplugin"
+	"github.com/mattmoor/korpc/pkg/templates"
 
 	// The protoc plugins that we have enabled.
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/api"
 	_ "github.com/mattmoor/korpc/pkg/protoplugin/config"
 	_ "github.com/mattmoor/korpc/pkg/protoplugin/entrypoint"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/fake"
 	_ "github.com/mattmoor/korpc/pkg/protoplugin/gateway"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/manifest"
 	_ "github.com/mattmoor/korpc/pkg/protoplugin/methods"
 	_ "github.com/mattmoor/korpc/pkg/protoplugin/scaffold"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/workspace"
 	// _ "github.com/mattmoor/korpc/pkg/protoplugin/sample"
 )
 
@@ -62,6 +67,7 @@ func main() {
 	cmds.AddCommand(delete.Command)
 	cmds.AddCommand(generate.Command)
 	cmds.AddCommand(install.Command)
+	cmds.AddCommand(templates.Command)
 
 	if err := cmds.Execute(); err != nil {
 		log.Fatalf("error during command execution: %v", err)
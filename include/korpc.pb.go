@@ -21,15 +21,77 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type Options_StreamStyle int32
+
+const (
+	// Impl is passed channels of requests and responses.
+	Options_CHANNELS Options_StreamStyle = 0
+	// Impl is passed the gRPC stream, from which it receives requests and to
+	// which it sends responses.  This lets Impl see when a send fails, and
+	// set headers and trailers.
+	Options_STREAM Options_StreamStyle = 1
+)
+
+var Options_StreamStyle_name = map[int32]string{
+	0: "CHANNELS",
+	1: "STREAM",
+}
+
+var Options_StreamStyle_value = map[string]int32{
+	"CHANNELS": 0,
+	"STREAM":   1,
+}
+
+func (x Options_StreamStyle) String() string {
+	return proto.EnumName(Options_StreamStyle_name, int32(x))
+}
+
+func (Options_StreamStyle) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{0, 0}
+}
+
 type Options struct {
 	ServiceAccount       string      `protobuf:"bytes,1,opt,name=service_account,json=serviceAccount,proto3" json:"service_account,omitempty"`
 	ContainerConcurrency int32       `protobuf:"varint,2,opt,name=container_concurrency,json=containerConcurrency,proto3" json:"container_concurrency,omitempty"`
 	Resources            *Resource   `protobuf:"bytes,3,opt,name=resources,proto3" json:"resources,omitempty"`
 	Env                  []*KeyValue `protobuf:"bytes,4,rep,name=env,proto3" json:"env,omitempty"`
 	TimeoutSeconds       int64       `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	// Setting metrics serves Prometheus metrics for the method alongside it,
+	// and annotates the Knative Service so that it is scraped.
+	Metrics *Metrics `protobuf:"bytes,6,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	// Telemetry configures where the method exports its OpenTelemetry traces
+	// and metrics.  The standard OTEL_* environment variables take precedence.
+	Telemetry *Telemetry `protobuf:"bytes,7,opt,name=telemetry,proto3" json:"telemetry,omitempty"`
+	// Logging configures the structured logger placed into the context passed
+	// to Impl, which logging.FromContext (pkg/runtime/logging) retrieves.
+	Logging *Logging `protobuf:"bytes,8,opt,name=logging,proto3" json:"logging,omitempty"`
+	// Requests are checked against their protoc-gen-validate rules before Impl
+	// is called, unless this is set.
+	SkipValidation bool `protobuf:"varint,9,opt,name=skip_validation,json=skipValidation,proto3" json:"skip_validation,omitempty"`
+	// Server tunes the gRPC server that hosts the method.
+	Server *Server `protobuf:"bytes,10,opt,name=server,proto3" json:"server,omitempty"`
+	// Setting auth requires callers to present a bearer token, whose verified
+	// claims auth.ClaimsFromContext (pkg/runtime/auth) retrieves in Impl.
+	Auth *Auth `protobuf:"bytes,11,opt,name=auth,proto3" json:"auth,omitempty"`
+	// Setting rate_limit rejects requests beyond the given limits with
+	// RESOURCE_EXHAUSTED, rather than queueing them.
+	RateLimit *RateLimit `protobuf:"bytes,12,opt,name=rate_limit,json=rateLimit,proto3" json:"rate_limit,omitempty"`
+	// Setting cache caches the responses of a unary method, which must have
+	// an idempotency_level of NO_SIDE_EFFECTS.
+	Cache *Cache `protobuf:"bytes,13,opt,name=cache,proto3" json:"cache,omitempty"`
+	// Stream configures the streams of a streaming method to outlive the
+	// revision's timeout_seconds by resuming.
+	Stream *Stream `protobuf:"bytes,14,opt,name=stream,proto3" json:"stream,omitempty"`
+	// StreamStyle selects how the Impl of a streaming method is passed its
+	// requests and responses.
+	StreamStyle Options_StreamStyle `protobuf:"varint,15,opt,name=stream_style,json=streamStyle,proto3,enum=korpc.Options_StreamStyle" json:"stream_style,omitempty"`
+	// ImplImportPath is the Go import path of the package that implements the
+	// method, in place of <base>/<methods-dir>/<service>/<method>.  It may be in
+	// another module, and is not scaffolded.
+	ImplImportPath       string   `protobuf:"bytes,16,opt,name=impl_import_path,json=implImportPath,proto3" json:"impl_import_path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Options) Reset()         { *m = Options{} }
@@ -92,6 +154,83 @@ func (m *Options) GetTimeoutSeconds() int64 {
 	return 0
 }
 
+func (m *Options) GetMetrics() *Metrics {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+func (m *Options) GetTelemetry() *Telemetry {
+	if m != nil {
+		return m.Telemetry
+	}
+	return nil
+}
+
+func (m *Options) GetLogging() *Logging {
+	if m != nil {
+		return m.Logging
+	}
+	return nil
+}
+
+func (m *Options) GetSkipValidation() bool {
+	if m != nil {
+		return m.SkipValidation
+	}
+	return false
+}
+
+func (m *Options) GetServer() *Server {
+	if m != nil {
+		return m.Server
+	}
+	return nil
+}
+
+func (m *Options) GetAuth() *Auth {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
+}
+
+func (m *Options) GetRateLimit() *RateLimit {
+	if m != nil {
+		return m.RateLimit
+	}
+	return nil
+}
+
+func (m *Options) GetCache() *Cache {
+	if m != nil {
+		return m.Cache
+	}
+	return nil
+}
+
+func (m *Options) GetStream() *Stream {
+	if m != nil {
+		return m.Stream
+	}
+	return nil
+}
+
+func (m *Options) GetStreamStyle() Options_StreamStyle {
+	if m != nil {
+		return m.StreamStyle
+	}
+	return Options_CHANNELS
+}
+
+func (m *Options) GetImplImportPath() string {
+	if m != nil {
+		return m.ImplImportPath
+	}
+	return ""
+}
+
 type KeyValue struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -233,6 +372,590 @@ func (m *Resource_Block) GetMemory() string {
 	return ""
 }
 
+type Metrics struct {
+	// The path on which to serve metrics, defaults to /metrics.
+	Path                 string   `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Metrics) Reset()         { *m = Metrics{} }
+func (m *Metrics) String() string { return proto.CompactTextString(m) }
+func (*Metrics) ProtoMessage()    {}
+func (*Metrics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{3}
+}
+
+func (m *Metrics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Metrics.Unmarshal(m, b)
+}
+func (m *Metrics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Metrics.Marshal(b, m, deterministic)
+}
+func (m *Metrics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Metrics.Merge(m, src)
+}
+func (m *Metrics) XXX_Size() int {
+	return xxx_messageInfo_Metrics.Size(m)
+}
+func (m *Metrics) XXX_DiscardUnknown() {
+	xxx_messageInfo_Metrics.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Metrics proto.InternalMessageInfo
+
+func (m *Metrics) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+type Telemetry struct {
+	// The host:port of the OTLP gRPC collector.
+	Endpoint string `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	// Whether to connect to the collector without TLS.
+	Insecure bool `protobuf:"varint,2,opt,name=insecure,proto3" json:"insecure,omitempty"`
+	// The fraction of new traces to sample, defaults to 1.
+	SampleRatio          float64  `protobuf:"fixed64,3,opt,name=sample_ratio,json=sampleRatio,proto3" json:"sample_ratio,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Telemetry) Reset()         { *m = Telemetry{} }
+func (m *Telemetry) String() string { return proto.CompactTextString(m) }
+func (*Telemetry) ProtoMessage()    {}
+func (*Telemetry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{4}
+}
+
+func (m *Telemetry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Telemetry.Unmarshal(m, b)
+}
+func (m *Telemetry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Telemetry.Marshal(b, m, deterministic)
+}
+func (m *Telemetry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Telemetry.Merge(m, src)
+}
+func (m *Telemetry) XXX_Size() int {
+	return xxx_messageInfo_Telemetry.Size(m)
+}
+func (m *Telemetry) XXX_DiscardUnknown() {
+	xxx_messageInfo_Telemetry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Telemetry proto.InternalMessageInfo
+
+func (m *Telemetry) GetEndpoint() string {
+	if m != nil {
+		return m.Endpoint
+	}
+	return ""
+}
+
+func (m *Telemetry) GetInsecure() bool {
+	if m != nil {
+		return m.Insecure
+	}
+	return false
+}
+
+func (m *Telemetry) GetSampleRatio() float64 {
+	if m != nil {
+		return m.SampleRatio
+	}
+	return 0
+}
+
+type Logging struct {
+	// Whether to log a line with the status and latency of every call.
+	AccessLog bool `protobuf:"varint,1,opt,name=access_log,json=accessLog,proto3" json:"access_log,omitempty"`
+	// The minimum level to log, e.g. "debug", defaults to "info".
+	Level                string   `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Logging) Reset()         { *m = Logging{} }
+func (m *Logging) String() string { return proto.CompactTextString(m) }
+func (*Logging) ProtoMessage()    {}
+func (*Logging) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{5}
+}
+
+func (m *Logging) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Logging.Unmarshal(m, b)
+}
+func (m *Logging) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Logging.Marshal(b, m, deterministic)
+}
+func (m *Logging) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Logging.Merge(m, src)
+}
+func (m *Logging) XXX_Size() int {
+	return xxx_messageInfo_Logging.Size(m)
+}
+func (m *Logging) XXX_DiscardUnknown() {
+	xxx_messageInfo_Logging.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Logging proto.InternalMessageInfo
+
+func (m *Logging) GetAccessLog() bool {
+	if m != nil {
+		return m.AccessLog
+	}
+	return false
+}
+
+func (m *Logging) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type Server struct {
+	// The largest message the method will receive, in bytes, defaults to 4MB.
+	MaxReceiveMessageBytes int32 `protobuf:"varint,1,opt,name=max_receive_message_bytes,json=maxReceiveMessageBytes,proto3" json:"max_receive_message_bytes,omitempty"`
+	// The largest message the method will send, in bytes.
+	MaxSendMessageBytes int32 `protobuf:"varint,2,opt,name=max_send_message_bytes,json=maxSendMessageBytes,proto3" json:"max_send_message_bytes,omitempty"`
+	// The maximum number of concurrent streams per connection.
+	MaxConcurrentStreams uint32 `protobuf:"varint,3,opt,name=max_concurrent_streams,json=maxConcurrentStreams,proto3" json:"max_concurrent_streams,omitempty"`
+	// Keepalive configures how connections are kept alive and aged out.
+	Keepalive *Keepalive `protobuf:"bytes,4,opt,name=keepalive,proto3" json:"keepalive,omitempty"`
+	// The compressors to register with the server, e.g. "gzip".
+	Compressors          []string `protobuf:"bytes,5,rep,name=compressors,proto3" json:"compressors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Server) Reset()         { *m = Server{} }
+func (m *Server) String() string { return proto.CompactTextString(m) }
+func (*Server) ProtoMessage()    {}
+func (*Server) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{6}
+}
+
+func (m *Server) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Server.Unmarshal(m, b)
+}
+func (m *Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Server.Marshal(b, m, deterministic)
+}
+func (m *Server) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Server.Merge(m, src)
+}
+func (m *Server) XXX_Size() int {
+	return xxx_messageInfo_Server.Size(m)
+}
+func (m *Server) XXX_DiscardUnknown() {
+	xxx_messageInfo_Server.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Server proto.InternalMessageInfo
+
+func (m *Server) GetMaxReceiveMessageBytes() int32 {
+	if m != nil {
+		return m.MaxReceiveMessageBytes
+	}
+	return 0
+}
+
+func (m *Server) GetMaxSendMessageBytes() int32 {
+	if m != nil {
+		return m.MaxSendMessageBytes
+	}
+	return 0
+}
+
+func (m *Server) GetMaxConcurrentStreams() uint32 {
+	if m != nil {
+		return m.MaxConcurrentStreams
+	}
+	return 0
+}
+
+func (m *Server) GetKeepalive() *Keepalive {
+	if m != nil {
+		return m.Keepalive
+	}
+	return nil
+}
+
+func (m *Server) GetCompressors() []string {
+	if m != nil {
+		return m.Compressors
+	}
+	return nil
+}
+
+type Keepalive struct {
+	// How long a connection may be idle before the server pings the client.
+	TimeSeconds int64 `protobuf:"varint,1,opt,name=time_seconds,json=timeSeconds,proto3" json:"time_seconds,omitempty"`
+	// How long the server waits for a ping to be acknowledged.
+	TimeoutSeconds int64 `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	// How long a connection may have no active calls before it is closed.
+	MaxConnectionIdleSeconds int64 `protobuf:"varint,3,opt,name=max_connection_idle_seconds,json=maxConnectionIdleSeconds,proto3" json:"max_connection_idle_seconds,omitempty"`
+	// How long a connection may live before it is gracefully closed.
+	MaxConnectionAgeSeconds int64 `protobuf:"varint,4,opt,name=max_connection_age_seconds,json=maxConnectionAgeSeconds,proto3" json:"max_connection_age_seconds,omitempty"`
+	// How long in-flight calls get to complete once a connection is too old.
+	MaxConnectionAgeGraceSeconds int64 `protobuf:"varint,5,opt,name=max_connection_age_grace_seconds,json=maxConnectionAgeGraceSeconds,proto3" json:"max_connection_age_grace_seconds,omitempty"`
+	// The minimum time clients must wait between pings.  Clients that ping
+	// more often have their connections closed.
+	MinTimeSeconds int64 `protobuf:"varint,6,opt,name=min_time_seconds,json=minTimeSeconds,proto3" json:"min_time_seconds,omitempty"`
+	// Whether clients may ping when there are no active streams.
+	PermitWithoutStream  bool     `protobuf:"varint,7,opt,name=permit_without_stream,json=permitWithoutStream,proto3" json:"permit_without_stream,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Keepalive) Reset()         { *m = Keepalive{} }
+func (m *Keepalive) String() string { return proto.CompactTextString(m) }
+func (*Keepalive) ProtoMessage()    {}
+func (*Keepalive) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{7}
+}
+
+func (m *Keepalive) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Keepalive.Unmarshal(m, b)
+}
+func (m *Keepalive) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Keepalive.Marshal(b, m, deterministic)
+}
+func (m *Keepalive) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Keepalive.Merge(m, src)
+}
+func (m *Keepalive) XXX_Size() int {
+	return xxx_messageInfo_Keepalive.Size(m)
+}
+func (m *Keepalive) XXX_DiscardUnknown() {
+	xxx_messageInfo_Keepalive.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Keepalive proto.InternalMessageInfo
+
+func (m *Keepalive) GetTimeSeconds() int64 {
+	if m != nil {
+		return m.TimeSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetTimeoutSeconds() int64 {
+	if m != nil {
+		return m.TimeoutSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetMaxConnectionIdleSeconds() int64 {
+	if m != nil {
+		return m.MaxConnectionIdleSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetMaxConnectionAgeSeconds() int64 {
+	if m != nil {
+		return m.MaxConnectionAgeSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetMaxConnectionAgeGraceSeconds() int64 {
+	if m != nil {
+		return m.MaxConnectionAgeGraceSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetMinTimeSeconds() int64 {
+	if m != nil {
+		return m.MinTimeSeconds
+	}
+	return 0
+}
+
+func (m *Keepalive) GetPermitWithoutStream() bool {
+	if m != nil {
+		return m.PermitWithoutStream
+	}
+	return false
+}
+
+type Auth struct {
+	// The issuer ("iss") that tokens must have.
+	Issuer string `protobuf:"bytes,1,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	// The audiences, one of which tokens must have ("aud").
+	Audiences []string `protobuf:"bytes,2,rep,name=audiences,proto3" json:"audiences,omitempty"`
+	// The path of a mounted JSON Web Key Set with which tokens are signed.
+	JwksFile string `protobuf:"bytes,3,opt,name=jwks_file,json=jwksFile,proto3" json:"jwks_file,omitempty"`
+	// The URL of a JSON Web Key Set with which tokens are signed.
+	JwksUrl string `protobuf:"bytes,4,opt,name=jwks_url,json=jwksUrl,proto3" json:"jwks_url,omitempty"`
+	// The scopes that tokens must all have ("scope" or "scp").
+	RequiredScopes       []string `protobuf:"bytes,5,rep,name=required_scopes,json=requiredScopes,proto3" json:"required_scopes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Auth) Reset()         { *m = Auth{} }
+func (m *Auth) String() string { return proto.CompactTextString(m) }
+func (*Auth) ProtoMessage()    {}
+func (*Auth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{8}
+}
+
+func (m *Auth) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Auth.Unmarshal(m, b)
+}
+func (m *Auth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Auth.Marshal(b, m, deterministic)
+}
+func (m *Auth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Auth.Merge(m, src)
+}
+func (m *Auth) XXX_Size() int {
+	return xxx_messageInfo_Auth.Size(m)
+}
+func (m *Auth) XXX_DiscardUnknown() {
+	xxx_messageInfo_Auth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Auth proto.InternalMessageInfo
+
+func (m *Auth) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *Auth) GetAudiences() []string {
+	if m != nil {
+		return m.Audiences
+	}
+	return nil
+}
+
+func (m *Auth) GetJwksFile() string {
+	if m != nil {
+		return m.JwksFile
+	}
+	return ""
+}
+
+func (m *Auth) GetJwksUrl() string {
+	if m != nil {
+		return m.JwksUrl
+	}
+	return ""
+}
+
+func (m *Auth) GetRequiredScopes() []string {
+	if m != nil {
+		return m.RequiredScopes
+	}
+	return nil
+}
+
+type RateLimit struct {
+	// The sustained rate of requests allowed for each key.
+	RequestsPerSecond float64 `protobuf:"fixed64,1,opt,name=requests_per_second,json=requestsPerSecond,proto3" json:"requests_per_second,omitempty"`
+	// How many requests for each key may arrive at once, defaults to the
+	// requests allowed in a second.
+	Burst int32 `protobuf:"varint,2,opt,name=burst,proto3" json:"burst,omitempty"`
+	// The metadata header that keys the quota, e.g. "x-api-key".  Requests
+	// share a single quota when neither this nor key_claim is set.
+	KeyHeader string `protobuf:"bytes,3,opt,name=key_header,json=keyHeader,proto3" json:"key_header,omitempty"`
+	// The verified token claim that keys the quota, e.g. "sub", which
+	// requires auth.
+	KeyClaim string `protobuf:"bytes,4,opt,name=key_claim,json=keyClaim,proto3" json:"key_claim,omitempty"`
+	// The maximum number of requests each instance handles at once.
+	MaxInFlight          int32    `protobuf:"varint,5,opt,name=max_in_flight,json=maxInFlight,proto3" json:"max_in_flight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RateLimit) Reset()         { *m = RateLimit{} }
+func (m *RateLimit) String() string { return proto.CompactTextString(m) }
+func (*RateLimit) ProtoMessage()    {}
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{9}
+}
+
+func (m *RateLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RateLimit.Unmarshal(m, b)
+}
+func (m *RateLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RateLimit.Marshal(b, m, deterministic)
+}
+func (m *RateLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RateLimit.Merge(m, src)
+}
+func (m *RateLimit) XXX_Size() int {
+	return xxx_messageInfo_RateLimit.Size(m)
+}
+func (m *RateLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_RateLimit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RateLimit proto.InternalMessageInfo
+
+func (m *RateLimit) GetRequestsPerSecond() float64 {
+	if m != nil {
+		return m.RequestsPerSecond
+	}
+	return 0
+}
+
+func (m *RateLimit) GetBurst() int32 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+func (m *RateLimit) GetKeyHeader() string {
+	if m != nil {
+		return m.KeyHeader
+	}
+	return ""
+}
+
+func (m *RateLimit) GetKeyClaim() string {
+	if m != nil {
+		return m.KeyClaim
+	}
+	return ""
+}
+
+func (m *RateLimit) GetMaxInFlight() int32 {
+	if m != nil {
+		return m.MaxInFlight
+	}
+	return 0
+}
+
+type Cache struct {
+	// How long responses are cached, defaults to 60 seconds.
+	TtlSeconds int64 `protobuf:"varint,1,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	// The maximum number of cached responses, defaults to 1000.
+	MaxEntries int32 `protobuf:"varint,2,opt,name=max_entries,json=maxEntries,proto3" json:"max_entries,omitempty"`
+	// The metadata headers whose values are part of the cache key, e.g. for
+	// responses that vary by locale.  With auth, the caller's verified
+	// identity is always part of the key.
+	KeyHeaders           []string `protobuf:"bytes,3,rep,name=key_headers,json=keyHeaders,proto3" json:"key_headers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Cache) Reset()         { *m = Cache{} }
+func (m *Cache) String() string { return proto.CompactTextString(m) }
+func (*Cache) ProtoMessage()    {}
+func (*Cache) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{10}
+}
+
+func (m *Cache) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Cache.Unmarshal(m, b)
+}
+func (m *Cache) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Cache.Marshal(b, m, deterministic)
+}
+func (m *Cache) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Cache.Merge(m, src)
+}
+func (m *Cache) XXX_Size() int {
+	return xxx_messageInfo_Cache.Size(m)
+}
+func (m *Cache) XXX_DiscardUnknown() {
+	xxx_messageInfo_Cache.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Cache proto.InternalMessageInfo
+
+func (m *Cache) GetTtlSeconds() int64 {
+	if m != nil {
+		return m.TtlSeconds
+	}
+	return 0
+}
+
+func (m *Cache) GetMaxEntries() int32 {
+	if m != nil {
+		return m.MaxEntries
+	}
+	return 0
+}
+
+func (m *Cache) GetKeyHeaders() []string {
+	if m != nil {
+		return m.KeyHeaders
+	}
+	return nil
+}
+
+type Stream struct {
+	// How often to send an empty response message on otherwise idle server
+	// streams, so that proxies do not drop them.  Clients should ignore these.
+	HeartbeatSeconds int64 `protobuf:"varint,1,opt,name=heartbeat_seconds,json=heartbeatSeconds,proto3" json:"heartbeat_seconds,omitempty"`
+	// How long a stream may run before it ends with a resumable status, which
+	// should be less than timeout_seconds.
+	MaxDurationSeconds   int64    `protobuf:"varint,2,opt,name=max_duration_seconds,json=maxDurationSeconds,proto3" json:"max_duration_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Stream) Reset()         { *m = Stream{} }
+func (m *Stream) String() string { return proto.CompactTextString(m) }
+func (*Stream) ProtoMessage()    {}
+func (*Stream) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d7ae5685d888d925, []int{11}
+}
+
+func (m *Stream) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Stream.Unmarshal(m, b)
+}
+func (m *Stream) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Stream.Marshal(b, m, deterministic)
+}
+func (m *Stream) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Stream.Merge(m, src)
+}
+func (m *Stream) XXX_Size() int {
+	return xxx_messageInfo_Stream.Size(m)
+}
+func (m *Stream) XXX_DiscardUnknown() {
+	xxx_messageInfo_Stream.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Stream proto.InternalMessageInfo
+
+func (m *Stream) GetHeartbeatSeconds() int64 {
+	if m != nil {
+		return m.HeartbeatSeconds
+	}
+	return 0
+}
+
+func (m *Stream) GetMaxDurationSeconds() int64 {
+	if m != nil {
+		return m.MaxDurationSeconds
+	}
+	return 0
+}
+
 var E_Options = &proto.ExtensionDesc{
 	ExtendedType:  (*descriptor.MethodOptions)(nil),
 	ExtensionType: (*Options)(nil),
@@ -243,43 +966,107 @@ var E_Options = &proto.ExtensionDesc{
 }
 
 func init() {
+	proto.RegisterEnum("korpc.Options_StreamStyle", Options_StreamStyle_name, Options_StreamStyle_value)
 	proto.RegisterType((*Options)(nil), "korpc.Options")
 	proto.RegisterType((*KeyValue)(nil), "korpc.KeyValue")
 	proto.RegisterType((*Resource)(nil), "korpc.Resource")
 	proto.RegisterMapType((map[string]string)(nil), "korpc.Resource.LimitsEntry")
 	proto.RegisterMapType((map[string]string)(nil), "korpc.Resource.RequestsEntry")
 	proto.RegisterType((*Resource_Block)(nil), "korpc.Resource.Block")
+	proto.RegisterType((*Metrics)(nil), "korpc.Metrics")
+	proto.RegisterType((*Telemetry)(nil), "korpc.Telemetry")
+	proto.RegisterType((*Logging)(nil), "korpc.Logging")
+	proto.RegisterType((*Server)(nil), "korpc.Server")
+	proto.RegisterType((*Keepalive)(nil), "korpc.Keepalive")
+	proto.RegisterType((*Auth)(nil), "korpc.Auth")
+	proto.RegisterType((*RateLimit)(nil), "korpc.RateLimit")
+	proto.RegisterType((*Cache)(nil), "korpc.Cache")
+	proto.RegisterType((*Stream)(nil), "korpc.Stream")
 	proto.RegisterExtension(E_Options)
 }
 
 func init() { proto.RegisterFile("korpc.proto", fileDescriptor_d7ae5685d888d925) }
 
 var fileDescriptor_d7ae5685d888d925 = []byte{
-	// 408 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0xcd, 0x6e, 0xd3, 0x40,
-	0x10, 0xc7, 0xb5, 0x71, 0x9d, 0x26, 0x13, 0x91, 0xa2, 0x55, 0x41, 0xab, 0x20, 0x90, 0xc9, 0x85,
-	0x5c, 0x70, 0x05, 0xe1, 0x40, 0xcb, 0x09, 0x10, 0xa7, 0x82, 0x90, 0x16, 0x89, 0x6b, 0x94, 0x6e,
-	0x86, 0x62, 0xd9, 0xde, 0x31, 0xfb, 0x11, 0xc9, 0x4f, 0xc3, 0x09, 0x89, 0xe7, 0xe2, 0x19, 0x78,
-	0x00, 0xe4, 0xf5, 0xba, 0x2d, 0x15, 0x17, 0x6e, 0x3b, 0xff, 0x0f, 0xeb, 0xb7, 0xde, 0x81, 0x59,
-	0x49, 0xa6, 0x51, 0x79, 0x63, 0xc8, 0x11, 0x4f, 0xc3, 0xb0, 0xc8, 0x2e, 0x89, 0x2e, 0x2b, 0x3c,
-	0x09, 0xe2, 0x85, 0xff, 0x72, 0xb2, 0x43, 0xab, 0x4c, 0xd1, 0x38, 0x32, 0x7d, 0x70, 0xf9, 0x8b,
-	0xc1, 0xe1, 0xc7, 0xc6, 0x15, 0xa4, 0x2d, 0x7f, 0x02, 0x47, 0x16, 0xcd, 0xbe, 0x50, 0xb8, 0xd9,
-	0x2a, 0x45, 0x5e, 0x3b, 0xc1, 0x32, 0xb6, 0x9a, 0xca, 0x79, 0x94, 0x5f, 0xf7, 0x2a, 0x5f, 0xc3,
-	0x3d, 0x45, 0xda, 0x6d, 0x0b, 0x8d, 0x66, 0xa3, 0x48, 0x2b, 0x6f, 0x0c, 0x6a, 0xd5, 0x8a, 0x51,
-	0xc6, 0x56, 0xa9, 0x3c, 0xbe, 0x32, 0xdf, 0x5e, 0x7b, 0xfc, 0x29, 0x4c, 0x0d, 0x5a, 0xf2, 0x46,
-	0xa1, 0x15, 0x49, 0xc6, 0x56, 0xb3, 0xe7, 0x47, 0x79, 0xcf, 0x2c, 0xa3, 0x2e, 0xaf, 0x13, 0xfc,
-	0x31, 0x24, 0xa8, 0xf7, 0xe2, 0x20, 0x4b, 0x6e, 0x04, 0xcf, 0xb1, 0xfd, 0xbc, 0xad, 0x3c, 0xca,
-	0xce, 0xeb, 0x78, 0x5d, 0x51, 0x23, 0x79, 0xb7, 0xb1, 0xa8, 0x48, 0xef, 0xac, 0x48, 0x33, 0xb6,
-	0x4a, 0xe4, 0x3c, 0xca, 0x9f, 0x7a, 0x75, 0xf9, 0x02, 0x26, 0x43, 0x93, 0x73, 0x38, 0xd0, 0xdb,
-	0x1a, 0xe3, 0xcd, 0xc2, 0x99, 0x1f, 0x43, 0xba, 0xef, 0xcc, 0xc0, 0x3f, 0x95, 0xfd, 0xb0, 0xfc,
-	0x31, 0x82, 0xc9, 0x40, 0xc6, 0xd7, 0x30, 0xae, 0x8a, 0xba, 0x70, 0x56, 0xb0, 0x40, 0xf4, 0xe0,
-	0x16, 0x7a, 0xfe, 0x3e, 0xb8, 0xef, 0xb4, 0x33, 0xad, 0x8c, 0x51, 0x7e, 0x0a, 0x13, 0x83, 0xdf,
-	0x3c, 0x5a, 0x67, 0xc5, 0x28, 0xd4, 0x1e, 0xde, 0xae, 0xc9, 0xe8, 0xf7, 0xc5, 0xab, 0xf8, 0xe2,
-	0x19, 0xa4, 0x6f, 0x2a, 0x52, 0x25, 0xbf, 0x0b, 0x89, 0x6a, 0x7c, 0xc4, 0xed, 0x8e, 0xfc, 0x3e,
-	0x8c, 0x6b, 0xac, 0xc9, 0xb4, 0x11, 0x37, 0x4e, 0x8b, 0x53, 0x98, 0xdd, 0x80, 0xe8, 0x8a, 0x25,
-	0xb6, 0x43, 0xb1, 0xc4, 0xf6, 0xdf, 0xd7, 0x3c, 0x1b, 0xbd, 0x64, 0x8b, 0x57, 0x70, 0xe7, 0x2f,
-	0x90, 0xff, 0x29, 0x9f, 0x9d, 0xc3, 0x21, 0xc5, 0x0d, 0x7a, 0x94, 0xf7, 0x0b, 0x97, 0x0f, 0x0b,
-	0x97, 0x7f, 0x40, 0xf7, 0x95, 0x76, 0x71, 0xc3, 0xc4, 0xf7, 0x9f, 0xbf, 0xf3, 0xf0, 0xf2, 0xf3,
-	0xf8, 0x1f, 0xa2, 0x21, 0x87, 0x2f, 0x5c, 0x8c, 0x43, 0x73, 0xfd, 0x27, 0x00, 0x00, 0xff, 0xff,
-	0xd2, 0x84, 0xa4, 0x24, 0xce, 0x02, 0x00, 0x00,
+	// 1276 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x4d, 0x73, 0x13, 0x47,
+	0x13, 0x7e, 0x57, 0xd6, 0xd7, 0xb6, 0x6c, 0x23, 0x86, 0x8f, 0x77, 0x31, 0x10, 0xc4, 0x56, 0xa5,
+	0x50, 0x2a, 0x15, 0x91, 0x60, 0x0e, 0x01, 0x8a, 0x54, 0x19, 0xc7, 0x04, 0x82, 0x21, 0xd4, 0x98,
+	0x90, 0xe3, 0xd6, 0x78, 0xd5, 0x96, 0x06, 0xed, 0x57, 0x66, 0x66, 0x85, 0x74, 0xcb, 0x1f, 0x49,
+	0xe5, 0x94, 0xaa, 0x5c, 0xf3, 0xa3, 0xf2, 0x0f, 0x72, 0xc9, 0x2d, 0x35, 0x1f, 0xbb, 0xb2, 0x54,
+	0xbe, 0xe4, 0x36, 0xfd, 0x3c, 0xdd, 0x33, 0x3d, 0x3d, 0xdd, 0xcf, 0x2e, 0xf4, 0x66, 0xb9, 0x28,
+	0xe2, 0x51, 0x21, 0x72, 0x95, 0x93, 0x96, 0x31, 0xf6, 0x06, 0x93, 0x3c, 0x9f, 0x24, 0x78, 0xdf,
+	0x80, 0xa7, 0xe5, 0xd9, 0xfd, 0x31, 0xca, 0x58, 0xf0, 0x42, 0xe5, 0xc2, 0x3a, 0x86, 0x7f, 0xb5,
+	0xa0, 0xf3, 0x43, 0xa1, 0x78, 0x9e, 0x49, 0x72, 0x0f, 0x2e, 0x49, 0x14, 0x73, 0x1e, 0x63, 0xc4,
+	0xe2, 0x38, 0x2f, 0x33, 0x15, 0x78, 0x03, 0x6f, 0xe8, 0xd3, 0x5d, 0x07, 0x1f, 0x58, 0x94, 0xec,
+	0xc3, 0xb5, 0x38, 0xcf, 0x14, 0xe3, 0x19, 0x8a, 0x28, 0xce, 0xb3, 0xb8, 0x14, 0x02, 0xb3, 0x78,
+	0x19, 0x34, 0x06, 0xde, 0xb0, 0x45, 0xaf, 0xd6, 0xe4, 0xe1, 0x8a, 0x23, 0x5f, 0x80, 0x2f, 0x50,
+	0xe6, 0xa5, 0x88, 0x51, 0x06, 0x5b, 0x03, 0x6f, 0xd8, 0x7b, 0x70, 0x69, 0x64, 0x73, 0xa6, 0x0e,
+	0xa7, 0x2b, 0x0f, 0x72, 0x17, 0xb6, 0x30, 0x9b, 0x07, 0xcd, 0xc1, 0xd6, 0x39, 0xc7, 0x57, 0xb8,
+	0x7c, 0xcf, 0x92, 0x12, 0xa9, 0xe6, 0x74, 0xbe, 0x8a, 0xa7, 0x98, 0x97, 0x2a, 0x92, 0x18, 0xe7,
+	0xd9, 0x58, 0x06, 0xad, 0x81, 0x37, 0xdc, 0xa2, 0xbb, 0x0e, 0x3e, 0xb1, 0x28, 0x19, 0x42, 0x27,
+	0x45, 0x25, 0x78, 0x2c, 0x83, 0xb6, 0x39, 0x78, 0xd7, 0xed, 0xf7, 0xda, 0xa2, 0xb4, 0xa2, 0xc9,
+	0x08, 0x7c, 0x85, 0x09, 0x6a, 0x73, 0x19, 0x74, 0x8c, 0x6f, 0xdf, 0xf9, 0xbe, 0xab, 0x70, 0xba,
+	0x72, 0xd1, 0x3b, 0x27, 0xf9, 0x64, 0xc2, 0xb3, 0x49, 0xd0, 0x5d, 0xdb, 0xf9, 0xd8, 0xa2, 0xb4,
+	0xa2, 0x4d, 0x71, 0x67, 0xbc, 0x88, 0xe6, 0x2c, 0xe1, 0x63, 0xa6, 0x0b, 0x1e, 0xf8, 0x03, 0x6f,
+	0xd8, 0xa5, 0xbb, 0x1a, 0x7e, 0x5f, 0xa3, 0xe4, 0x53, 0x68, 0xeb, 0x72, 0xa3, 0x08, 0xc0, 0xec,
+	0xb8, 0xe3, 0x76, 0x3c, 0x31, 0x20, 0x75, 0x24, 0xb9, 0x03, 0x4d, 0x56, 0xaa, 0x69, 0xd0, 0x33,
+	0x4e, 0x3d, 0xe7, 0x74, 0x50, 0xaa, 0x29, 0x35, 0x04, 0xb9, 0x0f, 0x20, 0x98, 0xc2, 0x28, 0xe1,
+	0x29, 0x57, 0xc1, 0xf6, 0xda, 0x5d, 0x28, 0x53, 0x78, 0xac, 0x71, 0xea, 0x8b, 0x6a, 0x49, 0x42,
+	0x68, 0xc5, 0x2c, 0x9e, 0x62, 0xb0, 0x63, 0x7c, 0xb7, 0x9d, 0xef, 0xa1, 0xc6, 0xa8, 0xa5, 0x4c,
+	0x72, 0x4a, 0x20, 0x4b, 0x83, 0xdd, 0xf5, 0xe4, 0x0c, 0x48, 0x1d, 0x49, 0x9e, 0xc2, 0xb6, 0x5d,
+	0x45, 0x52, 0x2d, 0x13, 0x0c, 0x2e, 0x0d, 0xbc, 0xe1, 0xee, 0x83, 0x3d, 0xe7, 0xec, 0xfa, 0xcd,
+	0x05, 0x9d, 0x68, 0x0f, 0xda, 0x93, 0x2b, 0x83, 0x0c, 0xa1, 0xcf, 0xd3, 0x22, 0x89, 0x78, 0x5a,
+	0xe4, 0x42, 0x45, 0x05, 0x53, 0xd3, 0xa0, 0x6f, 0x3b, 0x51, 0xe3, 0x2f, 0x0d, 0xfc, 0x96, 0xa9,
+	0x69, 0x78, 0x0f, 0x7a, 0xe7, 0x76, 0x21, 0xdb, 0xd0, 0x3d, 0x7c, 0x71, 0xf0, 0xe6, 0xcd, 0xd1,
+	0xf1, 0x49, 0xff, 0x7f, 0x04, 0xa0, 0x7d, 0xf2, 0x8e, 0x1e, 0x1d, 0xbc, 0xee, 0x7b, 0xe1, 0x43,
+	0xe8, 0x56, 0xcd, 0x43, 0x08, 0x34, 0x33, 0x96, 0xa2, 0x6b, 0x6e, 0xb3, 0x26, 0x57, 0xa1, 0x35,
+	0xd7, 0xa4, 0x69, 0x61, 0x9f, 0x5a, 0x23, 0xfc, 0xbd, 0x01, 0xdd, 0xaa, 0x39, 0xc9, 0x3e, 0xb4,
+	0x4d, 0x2d, 0x65, 0xe0, 0x99, 0xa6, 0xbc, 0xb9, 0xd1, 0xbd, 0x23, 0x53, 0x46, 0x79, 0x94, 0xe9,
+	0x1e, 0x71, 0xae, 0xe4, 0x11, 0x74, 0x05, 0xfe, 0x5c, 0xa2, 0x54, 0x32, 0x68, 0x98, 0xb0, 0xdb,
+	0x9b, 0x61, 0xd4, 0xf1, 0x36, 0xb0, 0x76, 0xdf, 0xfb, 0x0a, 0x5a, 0xcf, 0x92, 0x3c, 0x9e, 0x91,
+	0x3e, 0x6c, 0xc5, 0x45, 0xe9, 0xd2, 0xd5, 0x4b, 0x72, 0x1d, 0xda, 0x29, 0xa6, 0xb9, 0x58, 0xba,
+	0x74, 0x9d, 0xb5, 0xf7, 0x08, 0x7a, 0xe7, 0x92, 0xd0, 0x81, 0x33, 0x5c, 0x56, 0x81, 0x33, 0x5c,
+	0x5e, 0x7c, 0xcd, 0xc7, 0x8d, 0xaf, 0xbd, 0xbd, 0x27, 0xb0, 0xb3, 0x96, 0xc8, 0x7f, 0x09, 0x0e,
+	0x3f, 0x83, 0x8e, 0x1b, 0x25, 0x5d, 0x5c, 0xf3, 0x5e, 0xd6, 0xc7, 0xac, 0xbf, 0x6f, 0x76, 0xbd,
+	0x7e, 0x83, 0x36, 0xf5, 0xab, 0x85, 0x67, 0xe0, 0xd7, 0x93, 0x44, 0xf6, 0xa0, 0x8b, 0xd9, 0xb8,
+	0xc8, 0x79, 0x2d, 0x35, 0xb5, 0xad, 0x39, 0x9e, 0x49, 0x8c, 0x4b, 0x61, 0x0f, 0xec, 0xd2, 0xda,
+	0x26, 0x77, 0x61, 0x5b, 0xb2, 0xb4, 0x48, 0x30, 0x12, 0x7a, 0x68, 0x8c, 0x9c, 0x78, 0xb4, 0x67,
+	0x31, 0xaa, 0xa1, 0xf0, 0x1b, 0xe8, 0xb8, 0x19, 0x24, 0xb7, 0x01, 0x58, 0x1c, 0xa3, 0x94, 0x51,
+	0x92, 0x4f, 0xcc, 0x39, 0x5d, 0xea, 0x5b, 0xe4, 0x38, 0x9f, 0xe8, 0x6b, 0x25, 0x38, 0xc7, 0xa4,
+	0xba, 0x96, 0x31, 0xc2, 0x5f, 0x1a, 0xd0, 0xb6, 0x23, 0x47, 0x1e, 0xc1, 0x8d, 0x94, 0x2d, 0x22,
+	0x81, 0x31, 0xf2, 0x39, 0x46, 0x29, 0x4a, 0xc9, 0x26, 0x18, 0x9d, 0x2e, 0x15, 0x4a, 0xb3, 0x5d,
+	0x8b, 0x5e, 0x4f, 0xd9, 0x82, 0x5a, 0xfe, 0xb5, 0xa5, 0x9f, 0x69, 0x96, 0xec, 0x83, 0x66, 0x22,
+	0x89, 0xd9, 0x78, 0x23, 0xce, 0x4a, 0xe5, 0x95, 0x94, 0x2d, 0x4e, 0x30, 0x1b, 0xaf, 0x05, 0x3d,
+	0xb4, 0x41, 0xb5, 0xb0, 0xaa, 0xc8, 0x0e, 0x87, 0x95, 0xcd, 0x1d, 0x7a, 0x35, 0x65, 0x8b, 0x5a,
+	0x59, 0x95, 0xed, 0x7f, 0x23, 0x5d, 0x33, 0xc4, 0x82, 0x25, 0x7c, 0x8e, 0x41, 0x73, 0x6d, 0xdc,
+	0x5f, 0x55, 0x38, 0x5d, 0xb9, 0x90, 0x01, 0xf4, 0xe2, 0x3c, 0x2d, 0x04, 0x4a, 0x99, 0x0b, 0xad,
+	0x9c, 0x5b, 0x43, 0x9f, 0x9e, 0x87, 0xc2, 0x7f, 0x1a, 0xe0, 0xd7, 0xa1, 0xba, 0xe6, 0x5a, 0x56,
+	0x6b, 0xa9, 0xf5, 0x8c, 0xd4, 0xf6, 0x34, 0x56, 0xe9, 0xec, 0x05, 0x82, 0xdc, 0xb8, 0x50, 0x90,
+	0x9f, 0xc2, 0x4d, 0x77, 0xc3, 0x0c, 0x63, 0x2d, 0x06, 0x11, 0x1f, 0x27, 0xab, 0xad, 0xb7, 0x4c,
+	0x50, 0x60, 0xaf, 0xe9, 0x3c, 0x5e, 0x8e, 0x93, 0xfa, 0x9c, 0x27, 0xb0, 0xb7, 0x11, 0xce, 0x26,
+	0xab, 0xe8, 0xa6, 0x89, 0xfe, 0xff, 0x5a, 0xf4, 0xc1, 0xa4, 0x0e, 0x7e, 0x0e, 0x83, 0x0b, 0x82,
+	0x27, 0x82, 0xc5, 0xb8, 0xf1, 0x19, 0xb9, 0xb5, 0xb9, 0xc5, 0x77, 0xda, 0x69, 0xf5, 0x51, 0xe9,
+	0xa7, 0x3c, 0x8b, 0xd6, 0x6a, 0xd2, 0xb6, 0xb7, 0x4d, 0x79, 0xf6, 0xee, 0x5c, 0x59, 0x1e, 0xc0,
+	0xb5, 0x02, 0x45, 0xca, 0x55, 0xf4, 0x91, 0xab, 0xa9, 0xa9, 0x8e, 0xd5, 0xd0, 0x8e, 0x69, 0xc5,
+	0x2b, 0x96, 0xfc, 0xc9, 0x72, 0xf6, 0x39, 0xc3, 0x5f, 0x3d, 0x68, 0x6a, 0x31, 0xd7, 0xa3, 0xce,
+	0xa5, 0x2c, 0x51, 0xb8, 0x01, 0x71, 0x16, 0xb9, 0x05, 0x3e, 0x2b, 0xc7, 0x1c, 0xb3, 0x18, 0xad,
+	0xb2, 0xf8, 0x74, 0x05, 0x90, 0x9b, 0xe0, 0x7f, 0xf8, 0x38, 0x93, 0xd1, 0x19, 0x4f, 0xd0, 0x94,
+	0xd3, 0xa7, 0x5d, 0x0d, 0x3c, 0xe7, 0x09, 0x92, 0x1b, 0x60, 0xd6, 0x51, 0x29, 0x12, 0x53, 0x2c,
+	0x9f, 0x76, 0xb4, 0xfd, 0xa3, 0x48, 0xf4, 0x0b, 0x6a, 0xfd, 0xe1, 0x02, 0xc7, 0x91, 0x8c, 0xf3,
+	0x02, 0xab, 0xc6, 0xd8, 0xad, 0xe0, 0x13, 0x83, 0x86, 0x7f, 0x7a, 0xe0, 0xd7, 0x5f, 0x11, 0x32,
+	0x82, 0x2b, 0x95, 0x6c, 0x45, 0x05, 0x0a, 0x57, 0x0f, 0x93, 0xb1, 0x47, 0x2f, 0x57, 0xd4, 0x5b,
+	0x14, 0xb6, 0x24, 0x7a, 0xe4, 0x4e, 0x4b, 0x21, 0x95, 0x9b, 0x02, 0x6b, 0xe8, 0x39, 0x9d, 0xe1,
+	0x32, 0x9a, 0x22, 0x1b, 0xa3, 0x70, 0x59, 0xfb, 0x33, 0x5c, 0xbe, 0x30, 0x80, 0xbe, 0x93, 0xa6,
+	0xe3, 0x84, 0xf1, 0xd4, 0xe5, 0xdd, 0x9d, 0xe1, 0xf2, 0x50, 0xdb, 0x24, 0x84, 0x1d, 0xfd, 0xaa,
+	0x3c, 0x8b, 0xce, 0x12, 0x3e, 0x99, 0x2a, 0xf3, 0x84, 0x2d, 0xda, 0x4b, 0xd9, 0xe2, 0x65, 0xf6,
+	0xdc, 0x40, 0xe1, 0x07, 0x68, 0x99, 0x8f, 0x19, 0xb9, 0x03, 0x3d, 0xa5, 0x92, 0x8d, 0x4e, 0x06,
+	0xa5, 0x92, 0xea, 0xc5, 0xee, 0x80, 0x0e, 0x8c, 0x30, 0x53, 0x82, 0xd7, 0xb3, 0x0a, 0x29, 0x5b,
+	0x1c, 0x59, 0x44, 0x3b, 0xac, 0x52, 0xd5, 0x0d, 0xab, 0x6b, 0x04, 0x75, 0xae, 0x32, 0x9c, 0x40,
+	0xdb, 0xbe, 0x24, 0xf9, 0x1c, 0x2e, 0x4f, 0x91, 0x09, 0x75, 0x8a, 0x4c, 0x6d, 0x1c, 0xd9, 0xaf,
+	0x89, 0xea, 0xe0, 0x2f, 0x41, 0x0f, 0x77, 0x34, 0x2e, 0x8d, 0xae, 0x65, 0x1b, 0x63, 0x44, 0x52,
+	0xb6, 0xf8, 0xd6, 0x51, 0x2e, 0xe2, 0xf1, 0x2b, 0xe8, 0xe4, 0xee, 0xff, 0xed, 0x93, 0x91, 0xfd,
+	0xdd, 0x1b, 0x55, 0xbf, 0x7b, 0xfa, 0xff, 0x66, 0x9a, 0x8f, 0xdd, 0xf7, 0x36, 0xf8, 0xed, 0x8f,
+	0xbf, 0x47, 0x6b, 0x3f, 0x29, 0x8e, 0xa0, 0xd5, 0x0e, 0xa7, 0x6d, 0x13, 0xb9, 0xff, 0x6f, 0x00,
+	0x00, 0x00, 0xff, 0xff, 0x42, 0xdd, 0x2d, 0x05, 0x4c, 0x0a, 0x00, 0x00,
 }
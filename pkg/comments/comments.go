@@ -0,0 +1,99 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package comments finds the comments on services and methods in their
+// proto definitions, and renders them for the generated code and config.
+package comments
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// The field numbers by which SourceCodeInfo locates services and methods.
+const (
+	serviceField = 6 // FileDescriptorProto.service
+	methodField  = 2 // ServiceDescriptorProto.method
+)
+
+// Method returns the leading comments of the method, which are empty if it
+// has none.  This requires the file's SourceCodeInfo, which protoc passes to
+// plugins.
+func Method(fd *descriptor.FileDescriptorProto, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) string {
+	si, mi := -1, -1
+	for i, s := range fd.Service {
+		if s == sdp {
+			si = i
+		}
+	}
+	for i, m := range sdp.Method {
+		if m == mdp {
+			mi = i
+		}
+	}
+	path := []int32{serviceField, int32(si), methodField, int32(mi)}
+
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		if equal(loc.Path, path) {
+			return loc.GetLeadingComments()
+		}
+	}
+	return ""
+}
+
+// Doc renders the method's comments as a Go doc comment, which notes that it
+// is deprecated when its proto says so.  The result is empty or ends with a
+// newline, so that it may be placed directly before the declaration.
+func Doc(fd *descriptor.FileDescriptorProto, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) string {
+	var lines []string
+	if c := strings.TrimRight(Method(fd, sdp, mdp), "\n"); c != "" {
+		for _, line := range strings.Split(c, "\n") {
+			if line != "" && !strings.HasPrefix(line, " ") {
+				line = " " + line
+			}
+			lines = append(lines, "//"+line)
+		}
+	}
+	if mdp.GetOptions().GetDeprecated() {
+		if len(lines) > 0 {
+			lines = append(lines, "//")
+		}
+		lines = append(lines, fmt.Sprintf("// Deprecated: %s.%s is marked deprecated in %s.",
+			sdp.GetName(), mdp.GetName(), fd.GetName()))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Description renders the method's comments on a single line, e.g. for an
+// annotation.
+func Description(fd *descriptor.FileDescriptorProto, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) string {
+	return strings.Join(strings.Fields(Method(fd, sdp, mdp)), " ")
+}
+
+func equal(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
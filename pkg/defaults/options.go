@@ -0,0 +1,85 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package defaults
+
+import (
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	korpc "github.com/mattmoor/korpc/include"
+)
+
+const (
+	// MetricsPath is the path on which metrics are served when unspecified.
+	MetricsPath = "/metrics"
+
+	// SampleRatio is the fraction of new traces sampled when unspecified.
+	SampleRatio = 1.0
+
+	// LogLevel is the minimum level logged when unspecified.
+	LogLevel = "info"
+
+	// CacheTTLSeconds is how long responses are cached when unspecified.
+	CacheTTLSeconds = 60
+
+	// CacheMaxEntries is how many responses are cached when unspecified.
+	CacheMaxEntries = 1000
+)
+
+// Options returns the korpc options with which the method is decorated, with
+// defaults filled in for anything left unspecified.
+func Options(mdp *descriptor.MethodDescriptorProto) *korpc.Options {
+	opts := &korpc.Options{}
+	if addr, err := proto.GetExtension(mdp.Options, korpc.E_Options); err == nil {
+		// Copy the options so that filling in defaults doesn't alter the descriptor.
+		opts = proto.Clone(addr.(*korpc.Options)).(*korpc.Options)
+	}
+
+	if m := opts.Metrics; m != nil && m.Path == "" {
+		m.Path = MetricsPath
+	}
+
+	if rl := opts.RateLimit; rl != nil && rl.Burst == 0 {
+		rl.Burst = int32(math.Max(1, math.Ceil(rl.RequestsPerSecond)))
+	}
+
+	if c := opts.Cache; c != nil {
+		if c.TtlSeconds == 0 {
+			c.TtlSeconds = CacheTTLSeconds
+		}
+		if c.MaxEntries == 0 {
+			c.MaxEntries = CacheMaxEntries
+		}
+	}
+
+	// Telemetry is always set up, so we always fill it in.
+	if opts.Telemetry == nil {
+		opts.Telemetry = &korpc.Telemetry{}
+	}
+	if opts.Telemetry.SampleRatio == 0 {
+		opts.Telemetry.SampleRatio = SampleRatio
+	}
+
+	// Every request gets a logger, so we always fill it in.
+	if opts.Logging == nil {
+		opts.Logging = &korpc.Logging{}
+	}
+	if opts.Logging.Level == "" {
+		opts.Logging.Level = LogLevel
+	}
+	return opts
+}
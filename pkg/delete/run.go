@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/mattmoor/korpc/pkg/install"
+	"github.com/mattmoor/korpc/pkg/project"
 )
 
 func kodelete() error {
@@ -41,6 +42,9 @@ func kodelete() error {
 }
 
 func run(cmd *cobra.Command, args []string) {
+	if _, err := project.Configure(cmd, env); err != nil {
+		log.Fatalf("Error reading %s: %v", project.Name, err)
+	}
 	if err := kodelete(); err != nil {
 		log.Fatalf("Error deleting API: %v", err)
 	}
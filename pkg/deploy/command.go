@@ -15,11 +15,16 @@
 package deploy
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
+
+	"github.com/mattmoor/korpc/pkg/project"
 )
 
 var (
 	gen string
+	env string
 
 	Command = &cobra.Command{
 		Use:   "deploy",
@@ -31,4 +36,7 @@ var (
 func init() {
 	Command.Flags().StringVarP(&gen, "gen", "G", "./gen",
 		"The directory under which to put generated code and configuration.")
+
+	Command.Flags().StringVar(&env, "env", os.Getenv(project.EnvVar),
+		"The environment in korpc.yaml whose settings to use.")
 }
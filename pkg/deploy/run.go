@@ -23,13 +23,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/mattmoor/korpc/pkg/install"
+	"github.com/mattmoor/korpc/pkg/project"
 )
 
 func gogenerate(pkg string) error {
 	cmd := exec.Command("go", "generate", pkg)
 
-	// Pass through our environment
-	cmd.Env = os.Environ()
+	// Pass through our environment, along with the environment of korpc.yaml
+	// that `korpc generate` should use.
+	cmd.Env = append(os.Environ(), project.EnvVar+"="+env)
 
 	// Pass through our stdfoo
 	cmd.Stderr = os.Stderr
@@ -56,6 +58,9 @@ func koapply() error {
 }
 
 func run(cmd *cobra.Command, args []string) {
+	if _, err := project.Configure(cmd, env); err != nil {
+		log.Fatalf("Error reading %s: %v", project.Name, err)
+	}
 	if err := gogenerate("."); err != nil {
 		log.Fatalf("Error generating API: %v", err)
 	}
@@ -15,21 +15,31 @@
 package generate
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
+
+	"github.com/mattmoor/korpc/pkg/project"
 )
 
 var (
-	base      string
-	gen       string
-	methods   string
-	domain    string
-	namespace string
+	base        string
+	gen         string
+	methods     string
+	domains     []string
+	includes    []string
+	namespace   string
+	prune       bool
+	templateDir string
+	modules     bool
+	env         string
 
 	Command = &cobra.Command{
 		Use:   "generate",
 		Short: "Generate the yaml and entrypoints for the API.",
 		Run:   run,
-		Args:  cobra.MinimumNArgs(1),
+		// The protos may instead be listed in korpc.yaml.
+		Args: cobra.ArbitraryArgs,
 	}
 )
 
@@ -46,6 +56,21 @@ func init() {
 	Command.Flags().StringVarP(&namespace, "namespace", "n", "default",
 		"The namespace into which we should deploy things.")
 
-	Command.Flags().StringVarP(&domain, "domain", "D", "",
-		"The domain on which Istio will serve the resulting API.")
+	Command.Flags().StringSliceVarP(&domains, "domain", "D", nil,
+		"The domains on which Istio will serve the resulting API.")
+
+	Command.Flags().StringSliceVarP(&includes, "include", "I", nil,
+		"Additional directories in which protoc searches for imports.")
+
+	Command.Flags().StringVar(&templateDir, "templates", "",
+		"A directory of templates that override the defaults (see `korpc templates dump`).")
+
+	Command.Flags().BoolVar(&modules, "modules", false,
+		"Give each method, along with its entrypoint, its own Go module.")
+
+	Command.Flags().StringVar(&env, "env", os.Getenv(project.EnvVar),
+		"The environment in korpc.yaml whose settings to use.")
+
+	Command.Flags().BoolVar(&prune, "prune", false,
+		"Delete generated code and configuration for RPCs that no longer exist.")
 }
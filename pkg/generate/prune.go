@@ -0,0 +1,73 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mattmoor/korpc/pkg/manifest"
+)
+
+// cleanup reports what the previous run generated that this one did not,
+// deleting it when --prune is passed, and warns about method packages that
+// no longer implement an RPC.  Those belong to the user, so are never deleted.
+func cleanup(old *manifest.Manifest) {
+	m, err := manifest.Load(filepath.Join(gen, manifest.Name))
+	if err != nil {
+		log.Fatalf("Error reading the manifest: %v", err)
+	}
+
+	for _, path := range append(m.Stale(old), legacy()...) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if !prune {
+			log.Printf("%s is no longer generated, rerun with --prune to delete it", path)
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			log.Fatalf("Error deleting %s: %v", path, err)
+		}
+		log.Printf("Deleted %s", path)
+
+		// Remove the directories this leaves empty, e.g. gen/entrypoint/<service>.
+		for dir := filepath.Dir(path); dir != filepath.Clean(gen) && dir != filepath.Clean(methods); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	orphans, err := m.Orphans(methods)
+	if err != nil {
+		log.Fatalf("Error listing the methods: %v", err)
+	}
+	for _, dir := range orphans {
+		log.Printf("WARNING: %s does not implement any RPC, delete it if it is no longer needed", dir)
+	}
+}
+
+// legacy returns the files that earlier versions generated without recording
+// them in the manifest: the //go:generate lines that ran protoc once per
+// method, which would overwrite what we generate now.
+func legacy() []string {
+	return []string{
+		filepath.Join(gen, "entrypoint", "korpc.go"),
+		filepath.Join(gen, "config", "korpc.go"),
+		filepath.Join(methods, "korpc.go"),
+	}
+}
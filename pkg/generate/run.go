@@ -17,42 +17,54 @@ package generate
 import (
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"github.com/mattmoor/korpc/pkg/install"
+	"github.com/mattmoor/korpc/pkg/manifest"
 	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/project"
 )
 
-func gogenerate(pkg string) error {
-	cmd := exec.Command("go", "generate", pkg)
-
-	// Pass through our environment
-	cmd.Env = os.Environ()
-
-	// Pass through our stdfoo
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-
-	// Run it.
-	return cmd.Run()
-}
-
 func run(cmd *cobra.Command, args []string) {
+	settings, err := project.Configure(cmd, env)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", project.Name, err)
+	}
+	if len(args) == 0 {
+		args = settings.Protos
+	}
+	if len(args) == 0 {
+		log.Fatalf("`korpc generate` requires the protos to generate, either as arguments or in %s", project.Name)
+	}
 	if base == "" {
-		log.Fatal("--base is a required option to `korpc generate`")
+		log.Fatalf("--base (or base in %s) is required by `korpc generate`", project.Name)
 	}
-	if domain == "" {
-		log.Fatal("--domain is a required option to `korpc generate`")
+	if len(domains) == 0 {
+		log.Fatalf("--domain (or domains in %s) is required by `korpc generate`", project.Name)
+	}
+	domain := domains[0]
+
+	// The plugins run from various directories, so they are given the
+	// absolute path of the project's templates.
+	if templateDir != "" {
+		abs, err := filepath.Abs(templateDir)
+		if err != nil {
+			log.Fatalf("Error resolving --templates: %v", err)
+		}
+		templateDir = abs
+	}
+
+	// Read what the previous run generated before it is overwritten.
+	old, err := manifest.Load(filepath.Join(gen, manifest.Name))
+	if err != nil {
+		log.Fatalf("Error reading the manifest: %v", err)
 	}
 
 	invocations := []struct {
 		PluginPath string
 		Params     parameter.Stuff
-		Generate   bool
 	}{{
 		PluginPath: install.ProtoCGenGoPath,
 		Params: parameter.Stuff{
@@ -62,6 +74,25 @@ func run(cmd *cobra.Command, args []string) {
 			MethodsDir:      methods,
 			Namespace:       namespace,
 			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
+			NestedDirectory: filepath.Join(gen, "proto"),
+		},
+	}, {
+		PluginPath: install.ProtoCGenValidatePath,
+		Params: parameter.Stuff{
+			Name:            "validate",
+			Base:            base,
+			GenDir:          gen,
+			MethodsDir:      methods,
+			Namespace:       namespace,
+			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
 			NestedDirectory: filepath.Join(gen, "proto"),
 		},
 	}, {
@@ -73,9 +104,12 @@ func run(cmd *cobra.Command, args []string) {
 			MethodsDir:      methods,
 			Namespace:       namespace,
 			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
 			NestedDirectory: filepath.Join(gen, "entrypoint"),
 		},
-		Generate: true,
 	}, {
 		PluginPath: install.KORPCPath,
 		Params: parameter.Stuff{
@@ -85,9 +119,12 @@ func run(cmd *cobra.Command, args []string) {
 			MethodsDir:      methods,
 			Namespace:       namespace,
 			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
 			NestedDirectory: filepath.Join(gen, "config"),
 		},
-		Generate: true,
 	}, {
 		PluginPath: install.KORPCPath,
 		Params: parameter.Stuff{
@@ -97,10 +134,43 @@ func run(cmd *cobra.Command, args []string) {
 			MethodsDir: methods,
 			Namespace:  namespace,
 			Domain:     domain,
+			Templates:  templateDir,
+			Modules:    modules,
+			Domains:    domains,
+			Includes:   includes,
 			// Put the gateway into config.
 			NestedDirectory: filepath.Join(gen, "config"),
 		},
-		Generate: false,
+	}, {
+		PluginPath: install.KORPCPath,
+		Params: parameter.Stuff{
+			Name:            "api",
+			Base:            base,
+			GenDir:          gen,
+			MethodsDir:      methods,
+			Namespace:       namespace,
+			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
+			NestedDirectory: filepath.Join(gen, "api"),
+		},
+	}, {
+		PluginPath: install.KORPCPath,
+		Params: parameter.Stuff{
+			Name:            "fake",
+			Base:            base,
+			GenDir:          gen,
+			MethodsDir:      methods,
+			Namespace:       namespace,
+			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
+			NestedDirectory: filepath.Join(gen, "fake"),
+		},
 	}, {
 		PluginPath: install.KORPCPath,
 		Params: parameter.Stuff{
@@ -110,29 +180,65 @@ func run(cmd *cobra.Command, args []string) {
 			MethodsDir:      methods,
 			Namespace:       namespace,
 			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
 			NestedDirectory: filepath.Join(methods),
 		},
-		Generate: true,
+	}, {
+		PluginPath: install.KORPCPath,
+		Params: parameter.Stuff{
+			Name:       "workspace",
+			Base:       base,
+			GenDir:     gen,
+			MethodsDir: methods,
+			Namespace:  namespace,
+			Domain:     domain,
+			Templates:  templateDir,
+			Modules:    modules,
+			Domains:    domains,
+			Includes:   includes,
+			// The go.work goes at the root of the repository.
+			NestedDirectory: ".",
+		},
+	}, {
+		PluginPath: install.KORPCPath,
+		Params: parameter.Stuff{
+			Name:            "manifest",
+			Base:            base,
+			GenDir:          gen,
+			MethodsDir:      methods,
+			Namespace:       namespace,
+			Domain:          domain,
+			Templates:       templateDir,
+			Modules:         modules,
+			Domains:         domains,
+			Includes:        includes,
+			NestedDirectory: gen,
+		},
 	}}
 
+	// The plugins for entrypoints, config and methods each generate every
+	// method's files in-process, so a single invocation of protoc (which
+	// parses the protos once) generates everything.
+	outputs := make([]install.Output, 0, len(invocations))
 	for _, inv := range invocations {
 		log.Printf("Generating %s...", inv.Params.Name)
 		if err := os.MkdirAll(inv.Params.NestedDirectory, 0777); err != nil {
 			log.Fatalf("Error creating output directory %q: %v", inv.Params.NestedDirectory, err)
 		}
-		if err := install.RunProtoC(inv.Params.NestedDirectory, inv.PluginPath, inv.Params, args...); err != nil {
-			log.Fatalf("Error running protoc on %q: %v", inv.Params.Name, err)
-		}
-		if !inv.Generate {
-			continue
-		}
-		if err := gogenerate("./" + inv.Params.NestedDirectory); err != nil {
-			log.Fatalf("Error running go generate on %q: %v", inv.Params.NestedDirectory, err)
-		}
+		outputs = append(outputs, install.Output{
+			Out:    inv.Params.NestedDirectory,
+			Plugin: inv.PluginPath,
+			Param:  inv.Params,
+		})
+	}
+	if err := install.RunProtoCOutputs(outputs, args...); err != nil {
+		log.Fatalf("Error running protoc: %v", err)
 	}
 
-	log.Print("korpc code-generation complete.")
-	log.Print("To generate the skeleton for the RPC methods run:\n  go generate ./pkg/methods/...")
-	log.Print("To generate the skeleton for a single newly-added method run:\n  go generate ./pkg/methods/<ServiceName>/<MethodName>")
+	cleanup(old)
 
+	log.Print("korpc code-generation complete.")
 }
@@ -0,0 +1,222 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gotypes resolves proto messages to the Go types that protoc-gen-go
+// generates for them, so that generated code can refer to messages from
+// other proto packages (e.g. google.protobuf.Empty) and nested messages.
+package gotypes
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/parameter"
+)
+
+// Import is a Go package that generated code imports under Alias.
+type Import struct {
+	Alias string
+	Path  string
+}
+
+type goType struct {
+	path string
+	name string
+}
+
+// Resolver resolves messages relative to the proto file being generated,
+// whose package generated code imports as "pb".
+type Resolver struct {
+	local    string
+	messages map[string]goType
+
+	imports []Import
+	aliases map[string]string
+}
+
+// New returns a Resolver for the messages of the request, relative to fd.
+func New(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest, fd *descriptor.FileDescriptorProto) *Resolver {
+	generated := make(map[string]struct{})
+	for _, file := range request.FileToGenerate {
+		generated[file] = struct{}{}
+	}
+
+	r := &Resolver{
+		local:    ImportPath(stuff, fd),
+		messages: make(map[string]goType),
+		aliases:  make(map[string]string),
+	}
+	for _, file := range request.ProtoFile {
+		// korpc generates the Go for the protos it is given under gen/proto,
+		// and everything else must say where its Go lives.
+		path := goPackage(file)
+		if _, ok := generated[file.GetName()]; ok {
+			path = ImportPath(stuff, file)
+		}
+		prefix := "."
+		if file.GetPackage() != "" {
+			prefix += file.GetPackage() + "."
+		}
+		r.add(path, prefix, nil, file.MessageType)
+	}
+	return r
+}
+
+func (r *Resolver) add(path, prefix string, outer []string, messages []*descriptor.DescriptorProto) {
+	for _, m := range messages {
+		names := append(append([]string{}, outer...), m.GetName())
+		r.messages[prefix+strings.Join(names, ".")] = goType{
+			path: path,
+			name: messageName(names),
+		}
+		r.add(path, prefix, names, m.NestedType)
+	}
+}
+
+// ImportPath returns the import path of the Go that korpc generates for the
+// given proto file.
+func ImportPath(stuff *parameter.Stuff, fd *descriptor.FileDescriptorProto) string {
+	return filepath.Join(stuff.Base, stuff.GenDir, "proto",
+		// protoc-gen-go includes directory names
+		filepath.Dir(fd.GetName()))
+}
+
+// goPackage returns the import path from the file's go_package option, or
+// "" if it has none.
+func goPackage(fd *descriptor.FileDescriptorProto) string {
+	gp := fd.GetOptions().GetGoPackage()
+	if i := strings.Index(gp, ";"); i >= 0 {
+		return gp[:i]
+	}
+	// Without a semicolon, go_package may be just a package name.
+	if strings.Contains(gp, "/") {
+		return gp
+	}
+	return ""
+}
+
+// GoType returns the Go type for the fully-qualified message name (as found
+// in e.g. MethodDescriptorProto.InputType), importing its package as needed.
+func (r *Resolver) GoType(name string) (string, error) {
+	t, ok := r.messages[name]
+	if !ok {
+		return "", fmt.Errorf("unknown message %s", name)
+	}
+	if t.path == "" {
+		return "", fmt.Errorf("unable to determine the Go package of %s, set its go_package option", name)
+	}
+	if t.path == r.local {
+		return "pb." + t.name, nil
+	}
+	return r.alias(t.path) + "." + t.name, nil
+}
+
+// alias returns the name under which path is imported, e.g. emptypb for
+// github.com/golang/protobuf/ptypes/empty.
+func (r *Resolver) alias(path string) string {
+	if alias, ok := r.aliases[path]; ok {
+		return alias
+	}
+
+	base := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			return c
+		case c >= 'A' && c <= 'Z':
+			return c - 'A' + 'a'
+		default:
+			return -1
+		}
+	}, filepath.Base(path))
+	if !strings.HasSuffix(base, "pb") {
+		base += "pb"
+	}
+	if base[0] >= '0' && base[0] <= '9' {
+		base = "p" + base
+	}
+
+	alias := base
+	for i := 2; r.taken(alias); i++ {
+		alias = fmt.Sprintf("%s%d", base, i)
+	}
+	r.aliases[path] = alias
+	r.imports = append(r.imports, Import{Alias: alias, Path: path})
+	return alias
+}
+
+func (r *Resolver) taken(alias string) bool {
+	if alias == "pb" {
+		return true
+	}
+	for _, imp := range r.imports {
+		if imp.Alias == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// Imports returns the packages that the types resolved so far require, in
+// addition to "pb".
+func (r *Resolver) Imports() []Import {
+	return r.imports
+}
+
+// messageName returns the name of the Go type that protoc-gen-go generates
+// for the message with the given (outer to inner) names.  Nested names are
+// not simply camel-cased and joined with underscores, e.g. Outer.inner_msg is
+// OuterInnerMsg while Outer.Inner is Outer_Inner.  This follows GoCamelCase
+// in google.golang.org/protobuf/internal/strs, on which protoc-gen-go is built.
+func messageName(names []string) string {
+	s := strings.Join(names, ".")
+	var t []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the dot, and capitalize what follows.
+		case c == '.':
+			t = append(t, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// Need a capital letter; make the '_' an 'X'.
+			t = append(t, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip the underscore, and capitalize what follows.
+		case isDigit(c):
+			t = append(t, c)
+		default:
+			if isLower(c) {
+				c ^= ' '
+			}
+			t = append(t, c)
+			for i+1 < len(s) && isLower(s[i+1]) {
+				i++
+				t = append(t, s[i])
+			}
+		}
+	}
+	return string(t)
+}
+
+func isLower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
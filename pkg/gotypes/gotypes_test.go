@@ -0,0 +1,41 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gotypes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageName(t *testing.T) {
+	// These are the names protoc-gen-go gives the messages.
+	tests := map[string]string{
+		"Request":              "Request",
+		"Response.Nested":      "Response_Nested",
+		"Response.inner_thing": "ResponseInnerThing",
+		"Outer.Middle.Inner":   "Outer_Middle_Inner",
+		"outer.Inner":          "Outer_Inner",
+		"Outer._hidden":        "Outer_XHidden",
+		"_leading":             "XLeading",
+		"HTTPRule":             "HTTPRule",
+		"v2_Request":           "V2_Request",
+		"Outer.inner2":         "OuterInner2",
+	}
+	for in, want := range tests {
+		if got := messageName(strings.Split(in, ".")); got != want {
+			t.Errorf("messageName(%s) = %s, wanted %s", in, got, want)
+		}
+	}
+}
@@ -34,6 +34,10 @@ const (
 	version      = "3.7.0"
 	platform     = "linux"
 	architecture = "x86_64"
+
+	// The validators are methods on the messages, so protoc-gen-validate
+	// must lay out its output the same way as protoc-gen-go.
+	goPaths = "paths=import"
 )
 
 var (
@@ -53,24 +57,64 @@ func toroot(out string, param parameter.Stuff) string {
 	return invert(filepath.Join(param.NestedDirectory, invert(out)))
 }
 
+// Output is a plugin for protoc to run, and the directory into which it
+// writes its files.
+type Output struct {
+	Out    string
+	Plugin string
+	Param  parameter.Stuff
+}
+
 func ProtoCCmd(out string, plugin string, param parameter.Stuff, protos ...string) (string, []string) {
+	return ProtoCOutputsCmd([]Output{{Out: out, Plugin: plugin, Param: param}}, protos...)
+}
+
+// ProtoCOutputsCmd returns the protoc command that parses the protos once,
+// and runs each of the plugins over them.
+func ProtoCOutputsCmd(outputs []Output, protos ...string) (string, []string) {
 	args := []string{
 		"-I" + ProtoCInclude,
 		"-I" + KORPCInclude,
-		"-I" + toroot(out, param),
-		"--plugin=protoc-gen-" + param.Name + "=" + plugin,
+		"-I" + ProtoCGenValidateInclude,
+	}
+	seen := make(map[string]struct{})
+	include := func(dir string) {
+		if _, ok := seen[dir]; ok {
+			return
+		}
+		seen[dir] = struct{}{}
+		args = append(args, "-I"+dir)
+	}
+	for _, o := range outputs {
+		include(toroot(o.Out, o.Param))
+		// The project's own include paths are relative to the root of the repository.
+		for _, dir := range o.Param.Includes {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(toroot(o.Out, o.Param), dir)
+			}
+			include(dir)
+		}
 	}
 
-	switch plugin {
-	case KORPCPath:
-		args = append(args,
-			"--"+param.Name+"_out="+out,
-			"--"+param.Name+"_opt="+param.MustEncode(),
-		)
-	case ProtoCGenGoPath:
-		args = append(args,
-			"--"+param.Name+"_out=plugins=grpc:"+out,
-		)
+	for _, o := range outputs {
+		param := o.Param
+		args = append(args, "--plugin=protoc-gen-"+param.Name+"="+o.Plugin)
+
+		switch o.Plugin {
+		case KORPCPath:
+			args = append(args,
+				"--"+param.Name+"_out="+o.Out,
+				"--"+param.Name+"_opt="+param.MustEncode(),
+			)
+		case ProtoCGenGoPath:
+			args = append(args,
+				"--"+param.Name+"_out=plugins=grpc,"+goPaths+":"+o.Out,
+			)
+		case ProtoCGenValidatePath:
+			args = append(args,
+				"--"+param.Name+"_out=lang=go,"+goPaths+":"+o.Out,
+			)
+		}
 	}
 
 	args = append(args, protos...)
@@ -78,7 +122,13 @@ func ProtoCCmd(out string, plugin string, param parameter.Stuff, protos ...strin
 }
 
 func RunProtoC(out string, plugin string, param parameter.Stuff, protos ...string) error {
-	binary, args := ProtoCCmd(out, plugin, param, protos...)
+	return RunProtoCOutputs([]Output{{Out: out, Plugin: plugin, Param: param}}, protos...)
+}
+
+// RunProtoCOutputs runs the plugins over the protos with a single invocation
+// of protoc.
+func RunProtoCOutputs(outputs []Output, protos ...string) error {
+	binary, args := ProtoCOutputsCmd(outputs, protos...)
 
 	cmd := exec.Command(binary, args...)
 
@@ -30,6 +30,9 @@ func run(cmd *cobra.Command, args []string) {
 	if err := InstallProtoCGenGo(); err != nil {
 		log.Fatalf("Error installing protoc-gen-go: %v", err)
 	}
+	if err := InstallProtoCGenValidate(); err != nil {
+		log.Fatalf("Error installing protoc-gen-validate: %v", err)
+	}
 	if err := InstallProtoC(); err != nil {
 		log.Fatalf("Error installing protoc: %v", err)
 	}
@@ -0,0 +1,131 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifest records what `korpc generate` produced for an API, so that
+// what it no longer produces can be found.
+package manifest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/mattmoor/korpc/pkg/defaults"
+	"github.com/mattmoor/korpc/pkg/naming"
+	"github.com/mattmoor/korpc/pkg/parameter"
+)
+
+// Name is the file under the generated directory that holds the manifest.
+const Name = "manifest.json"
+
+// Manifest lists paths relative to the root of the repository.
+type Manifest struct {
+	// Generated holds the per-service and per-method files and directories
+	// that korpc owns, and may delete once they are no longer generated.
+	Generated []string `json:"generated"`
+
+	// Methods holds the packages in which the methods are implemented.
+	// These belong to the user, so korpc never deletes them.
+	Methods []string `json:"methods"`
+}
+
+// New returns the manifest for the services defined in the given files.
+func New(stuff *parameter.Stuff, files []*descriptor.FileDescriptorProto) *Manifest {
+	m := &Manifest{}
+	for _, fd := range files {
+		for _, sdp := range fd.Service {
+			service := strings.ToLower(sdp.GetName())
+			m.Generated = append(m.Generated,
+				filepath.Join(stuff.GenDir, "api", service+".go"),
+				filepath.Join(stuff.GenDir, "fake", service+".go"))
+			for _, mdp := range sdp.Method {
+				method := strings.ToLower(mdp.GetName())
+				m.Generated = append(m.Generated,
+					filepath.Join(stuff.GenDir, "entrypoint", service, method),
+					filepath.Join(stuff.GenDir, "config", naming.Service(sdp, mdp)+".yaml"))
+				// Methods implemented elsewhere have no package here.
+				if defaults.Options(mdp).ImplImportPath != "" {
+					continue
+				}
+				m.Methods = append(m.Methods, filepath.Join(stuff.MethodsDir, service, method))
+			}
+		}
+	}
+	if stuff.Modules {
+		m.Generated = append(m.Generated, "go.work",
+			filepath.Join(stuff.GenDir, "proto", "go.mod"))
+	}
+	sort.Strings(m.Generated)
+	sort.Strings(m.Methods)
+	return m
+}
+
+// Load reads the manifest at path, which is empty if nothing has been
+// generated yet.
+func Load(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Stale returns the paths generated for old that are not generated for m.
+func (m *Manifest) Stale(old *Manifest) []string {
+	return difference(old.Generated, m.Generated)
+}
+
+// Orphans returns the method packages found under methodsDir (i.e. the
+// directories <methodsDir>/<service>/<method>) that are not in m.
+func (m *Manifest) Orphans(methodsDir string) ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(methodsDir, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, dir := range dirs {
+		if fi, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if fi.IsDir() {
+			found = append(found, dir)
+		}
+	}
+	return difference(found, m.Methods), nil
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		in[filepath.Clean(s)] = struct{}{}
+	}
+	var diff []string
+	for _, s := range a {
+		if _, ok := in[filepath.Clean(s)]; !ok {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package modules lays out the go.mod and go.work files with which each method,
+// along with its entrypoint, is built as its own Go module.  This lets methods
+// take on (and upgrade) dependencies without affecting one another.
+package modules
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mattmoor/korpc/pkg/parameter"
+)
+
+// DefaultGoVersion is the go directive of the files we create when the
+// repository doesn't have one, which is the earliest release that supports
+// workspaces.
+const DefaultGoVersion = "1.18"
+
+// Replace is a module that is required from a directory of the repository.
+type Replace struct {
+	// Path is the module's path.
+	Path string
+
+	// Dir is the module's directory, relative to the go.mod requiring it.
+	Dir string
+}
+
+// ProtoDir returns the directory of the module holding the generated proto
+// package, relative to the root of the repository.
+func ProtoDir(stuff *parameter.Stuff) string {
+	return filepath.Join(stuff.GenDir, "proto")
+}
+
+// EntrypointDir returns the directory of the module holding the method's
+// entrypoint, relative to the root of the repository.
+func EntrypointDir(stuff *parameter.Stuff, service, method string) string {
+	return filepath.Join(stuff.GenDir, "entrypoint", strings.ToLower(service), strings.ToLower(method))
+}
+
+// MethodDir returns the directory of the module holding the method's
+// implementation, relative to the root of the repository.
+func MethodDir(stuff *parameter.Stuff, service, method string) string {
+	return filepath.Join(stuff.MethodsDir, strings.ToLower(service), strings.ToLower(method))
+}
+
+// Path returns the path of the module in dir, relative to the root of the
+// repository.
+func Path(stuff *parameter.Stuff, dir string) string {
+	return filepath.Join(stuff.Base, dir)
+}
+
+// GoVersion returns the go directive for the files we create, given the root
+// of the repository.  A workspace may list no earlier version than any of its
+// modules, so this is taken from the existing go.work (e.g. once `go work use`
+// has updated it) or failing that from the go.mod of the root module.
+func GoVersion(root string) (string, error) {
+	for _, name := range []string{"go.work", "go.mod"} {
+		b, err := ioutil.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "go" {
+				return fields[1], nil
+			}
+		}
+	}
+	return DefaultGoVersion, nil
+}
+
+// GoMod renders the go.mod of the module at path, which requires the given
+// modules from their directories.  The rest of its requirements are left to
+// `go mod tidy`.
+func GoMod(path, goVersion string, replaces ...Replace) (string, error) {
+	return execToString(goModTemplate, struct {
+		Path      string
+		GoVersion string
+		Replaces  []Replace
+	}{path, goVersion, replaces})
+}
+
+// GoWork renders a go.work that uses the modules in the given directories,
+// relative to the root of the repository.
+func GoWork(goVersion string, dirs []string) (string, error) {
+	return execToString(goWorkTemplate, struct {
+		GoVersion string
+		Dirs      []string
+	}{goVersion, dirs})
+}
+
+var (
+	goModTemplate = template.Must(template.New("go.mod").Parse(`module {{.Path}}
+
+go {{.GoVersion}}
+{{if .Replaces}}
+require ({{range .Replaces}}
+	{{.Path}} v0.0.0{{end}}
+)
+
+replace ({{range .Replaces}}
+	{{.Path}} => {{.Dir}}{{end}}
+)
+{{end}}`))
+
+	goWorkTemplate = template.Must(template.New("go.work").Parse(`go {{.GoVersion}}
+
+use ({{range .Dirs}}
+	{{.}}{{end}}
+)
+`))
+)
+
+// execute a template to produce a string.
+func execToString(t *template.Template, opt interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := t.Execute(buf, opt)
+	if err != nil {
+		return "", err
+	}
+	return string(buf.Bytes()), nil
+}
@@ -31,6 +31,21 @@ type Stuff struct {
 	Domain     string `json:"domain,omitempty"`
 	Namespace  string `json:"namespace,omitempty"`
 
+	// Templates is the absolute path of the directory holding the project's
+	// overrides of the default templates, if any.
+	Templates string `json:"templates,omitempty"`
+
+	// Domains are all of the domains on which the API is served, the first
+	// of which is Domain.
+	Domains []string `json:"domains,omitempty"`
+
+	// Includes are additional directories, relative to the root of the
+	// repository, in which protoc searches for imports.
+	Includes []string `json:"includes,omitempty"`
+
+	// Modules gives each method, along with its entrypoint, its own Go module.
+	Modules bool `json:"modules,omitempty"`
+
 	Service         string `json:"service,omitempty"`
 	Method          string `json:"method,omitempty"`
 	NestedDirectory string `json:"nested_directory,omitempty"`
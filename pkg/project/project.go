@@ -0,0 +1,223 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package project reads korpc.yaml, which holds the settings of a project that
+// would otherwise be passed to each korpc command as flags.
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// Name is the file, at the root of the repository, that holds the settings.
+const Name = "korpc.yaml"
+
+// EnvVar names the environment to use when --env isn't passed, which lets
+// `korpc deploy` select the environment of the `korpc generate` it runs.
+const EnvVar = "KORPC_ENV"
+
+// Settings are the values that korpc.yaml may set, each of which takes the
+// place of the flag of the same name.  Paths are relative to the root of the
+// repository.
+type Settings struct {
+	// Base is the base import path for packages in this repository.
+	Base string `yaml:"base,omitempty"`
+
+	// Gen is the directory under which to put generated code and configuration.
+	Gen string `yaml:"gen,omitempty"`
+
+	// Methods is the directory under which to find RPC method implementations.
+	Methods string `yaml:"methods,omitempty"`
+
+	// Protos are the .proto files that define the API.
+	Protos []string `yaml:"protos,omitempty"`
+
+	// Includes are additional directories in which protoc searches for imports.
+	Includes []string `yaml:"includes,omitempty"`
+
+	// Namespace is the namespace into which we should deploy things.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Domains are the domains on which Istio will serve the resulting API.
+	Domains []string `yaml:"domains,omitempty"`
+
+	// Templates is a directory of templates that override the defaults.
+	Templates string `yaml:"templates,omitempty"`
+
+	// Modules gives each method, along with its entrypoint, its own Go module.
+	// It is a pointer so that an environment may turn it off.
+	Modules *bool `yaml:"modules,omitempty"`
+}
+
+// Config is the content of korpc.yaml.
+type Config struct {
+	Settings `yaml:",inline"`
+
+	// Environments are named sets of settings (e.g. dev, staging and prod),
+	// which override those above with any values they set.
+	Environments map[string]Settings `yaml:"environments,omitempty"`
+}
+
+// Load reads korpc.yaml from dir, which is empty if there is none.
+func Load(dir string) (*Config, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, Name))
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// For returns the settings of the named environment, or those of the project
+// itself when env is empty.
+func (c *Config) For(env string) (*Settings, error) {
+	s := c.Settings
+	if env == "" {
+		return &s, nil
+	}
+	o, ok := c.Environments[env]
+	if !ok {
+		var names []string
+		for name := range c.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("environment %q is not defined, want one of: %v", env, names)
+	}
+
+	if o.Base != "" {
+		s.Base = o.Base
+	}
+	if o.Gen != "" {
+		s.Gen = o.Gen
+	}
+	if o.Methods != "" {
+		s.Methods = o.Methods
+	}
+	if len(o.Protos) != 0 {
+		s.Protos = o.Protos
+	}
+	if len(o.Includes) != 0 {
+		s.Includes = o.Includes
+	}
+	if o.Namespace != "" {
+		s.Namespace = o.Namespace
+	}
+	if len(o.Domains) != 0 {
+		s.Domains = o.Domains
+	}
+	if o.Templates != "" {
+		s.Templates = o.Templates
+	}
+	if o.Modules != nil {
+		s.Modules = o.Modules
+	}
+	return &s, nil
+}
+
+// Apply sets each of the flags that wasn't passed to the value of the same
+// setting, if any.  Flags that a command doesn't have are skipped.
+func (s *Settings) Apply(flags *pflag.FlagSet) error {
+	for name, value := range s.values() {
+		f := flags.Lookup(name)
+		if f == nil || f.Changed || value == "" {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Conflicts describes the flags that were passed with other values than the
+// settings give them, which the flags therefore win over.
+func (s *Settings) Conflicts(flags *pflag.FlagSet) []string {
+	var conflicts []string
+	for name, value := range s.values() {
+		f := flags.Lookup(name)
+		if f == nil || !f.Changed || value == "" {
+			continue
+		}
+		passed := f.Value.String()
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			passed = strings.Join(sv.GetSlice(), ",")
+		}
+		if passed != value {
+			conflicts = append(conflicts, fmt.Sprintf("--%s=%s (instead of %s)", name, passed, value))
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// values returns the settings by the names of their flags, with lists
+// joined by commas.
+func (s *Settings) values() map[string]string {
+	values := map[string]string{
+		"base":      s.Base,
+		"gen":       s.Gen,
+		"methods":   s.Methods,
+		"include":   strings.Join(s.Includes, ","),
+		"namespace": s.Namespace,
+		"domain":    strings.Join(s.Domains, ","),
+		"templates": s.Templates,
+	}
+	if s.Modules != nil {
+		values["modules"] = strconv.FormatBool(*s.Modules)
+	}
+	return values
+}
+
+// Configure applies the settings of the environment from the korpc.yaml in
+// the current directory to the flags of cmd, and returns them.  Flags that
+// were passed win, as usual, even over the settings of the environment, so
+// we warn about those that the environment would have changed (e.g. a
+// --domain on the //go:generate line).
+func Configure(cmd *cobra.Command, env string) (*Settings, error) {
+	c, err := Load(".")
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.For(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Apply(cmd.Flags()); err != nil {
+		return nil, err
+	}
+	if env != "" {
+		o := c.Environments[env]
+		for _, conflict := range o.Conflicts(cmd.Flags()) {
+			log.Printf("WARNING: environment %q is overridden by the flag %s", env, conflict)
+		}
+	}
+	return s, nil
+}
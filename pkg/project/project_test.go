@@ -0,0 +1,145 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+const config = `
+namespace: default
+domains:
+- dev.example.com
+modules: true
+environments:
+  prod:
+    namespace: prod
+    domains:
+    - example.com
+    - api.example.com
+    modules: false
+`
+
+func load(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, Name), []byte(config), 0644); err != nil {
+		t.Fatalf("WriteFile() = %v", err)
+	}
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	return c
+}
+
+// flags returns the flags of a command, as its //go:generate line passed
+// them.
+func flags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	fs := pflag.NewFlagSet("generate", pflag.ContinueOnError)
+	fs.String("namespace", "", "")
+	fs.StringSlice("domain", nil, "")
+	fs.Bool("modules", false, "")
+	fs.String("templates", "", "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse() = %v", err)
+	}
+	return fs
+}
+
+func TestFor(t *testing.T) {
+	c := load(t)
+
+	s, err := c.For("")
+	if err != nil {
+		t.Fatalf("For() = %v", err)
+	}
+	if s.Modules == nil || !*s.Modules {
+		t.Errorf("Modules = %v, wanted true", s.Modules)
+	}
+
+	s, err = c.For("prod")
+	if err != nil {
+		t.Fatalf("For(prod) = %v", err)
+	}
+	if s.Namespace != "prod" {
+		t.Errorf("Namespace = %q, wanted prod", s.Namespace)
+	}
+	if s.Modules == nil || *s.Modules {
+		t.Errorf("Modules = %v, wanted false", s.Modules)
+	}
+	if len(s.Domains) != 2 || s.Domains[0] != "example.com" {
+		t.Errorf("Domains = %v, wanted those of prod", s.Domains)
+	}
+	if s.Base != "" || s.Gen != "" {
+		t.Errorf("Settings = %+v, wanted only what the project and prod set", s)
+	}
+
+	if _, err := c.For("staging"); err == nil {
+		t.Error("For(staging) = nil, wanted an error")
+	}
+}
+
+func TestApply(t *testing.T) {
+	c := load(t)
+	s, err := c.For("")
+	if err != nil {
+		t.Fatalf("For() = %v", err)
+	}
+
+	fs := flags(t, "--namespace=mine")
+	if err := s.Apply(fs); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	// Flags that were passed win over the project's settings.
+	if got, _ := fs.GetString("namespace"); got != "mine" {
+		t.Errorf("namespace = %q, wanted mine", got)
+	}
+	if got, _ := fs.GetStringSlice("domain"); len(got) != 1 || got[0] != "dev.example.com" {
+		t.Errorf("domain = %v, wanted dev.example.com", got)
+	}
+	if got, _ := fs.GetBool("modules"); !got {
+		t.Error("modules = false, wanted true")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	c := load(t)
+	o := c.Environments["prod"]
+
+	fs := flags(t, "--namespace=prod", "--domain=mine.example.com", "--modules", "--templates=mine")
+	if err := o.Apply(fs); err != nil {
+		t.Fatalf("Apply() = %v", err)
+	}
+	// Flags that were passed win over the environment too, but those it
+	// would have changed are reported.
+	if got, _ := fs.GetStringSlice("domain"); len(got) != 1 || got[0] != "mine.example.com" {
+		t.Errorf("domain = %v, wanted mine.example.com", got)
+	}
+	got := o.Conflicts(fs)
+	want := []string{
+		"--domain=mine.example.com (instead of example.com,api.example.com)",
+		"--modules=true (instead of false)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Conflicts() = %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/naming"
+	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/protoplugin"
+)
+
+type plugin struct {
+}
+
+var _ protoplugin.Interface = (*plugin)(nil)
+
+var (
+	docTmpl     = template.Must(template.New("doc").Parse(docTemplate))
+	serviceTmpl = template.Must(template.New("service").Parse(serviceTemplate))
+)
+
+func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	codegen := make(map[string]struct{})
+	for _, file := range request.FileToGenerate {
+		codegen[file] = struct{}{}
+	}
+
+	var resp plugin_go.CodeGeneratorResponse
+	docName := "doc.go"
+	docContent, err := execToString(docTmpl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+		Name:    &docName,
+		Content: &docContent,
+	})
+
+	for _, fd := range request.ProtoFile {
+		if _, ok := codegen[fd.GetName()]; !ok {
+			continue
+		}
+
+		for _, sdp := range fd.Service {
+			r := gotypes.New(stuff, request, fd)
+			opt := &options{
+				Service:         sdp.GetName(),
+				FullService:     fmt.Sprintf("%s.%s", fd.GetPackage(), sdp.GetName()),
+				Namespace:       stuff.Namespace,
+				ProtoImportPath: gotypes.ImportPath(stuff, fd),
+				Key:             lowerFirst(sdp.GetName()) + "Key",
+			}
+			for _, mdp := range sdp.Method {
+				requestType, err := r.GoType(mdp.GetInputType())
+				if err != nil {
+					return nil, err
+				}
+				responseType, err := r.GoType(mdp.GetOutputType())
+				if err != nil {
+					return nil, err
+				}
+				opt.Methods = append(opt.Methods, method{
+					Method:          mdp.GetName(),
+					Field:           lowerFirst(mdp.GetName()) + "Client",
+					ServiceName:     naming.Service(sdp, mdp),
+					RequestType:     requestType,
+					ResponseType:    responseType,
+					ClientStreaming: mdp.GetClientStreaming(),
+					ServerStreaming: mdp.GetServerStreaming(),
+				})
+			}
+			opt.Imports = r.Imports()
+
+			name := strings.ToLower(sdp.GetName()) + ".go"
+			content, err := execToString(serviceTmpl, opt)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+				Name:    &name,
+				Content: &content,
+			})
+		}
+	}
+	return &resp, nil
+}
+
+// lowerFirst lowercases the first letter of an exported name.
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// execute a template to produce a string.
+func execToString(t *template.Template, opt interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := t.Execute(buf, opt)
+	if err != nil {
+		return "", err
+	}
+	return string(buf.Bytes()), nil
+}
+
+func init() {
+	protoplugin.Register("api", &plugin{})
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/mattmoor/korpc/pkg/gotypes"
+)
+
+type options struct {
+	Service         string
+	FullService     string
+	Namespace       string
+	ProtoImportPath string
+	Imports         []gotypes.Import
+	Key             string
+	Methods         []method
+}
+
+type method struct {
+	Method          string
+	Field           string
+	ServiceName     string
+	RequestType     string
+	ResponseType    string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+const (
+	docTemplate = `// Package api contains clients for calling the methods of this API from
+// other methods, which dial each method's Knative Service directly over the
+// cluster network instead of going out through the ingress.
+package api
+`
+
+	serviceTemplate = `package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/mattmoor/korpc/pkg/runtime/client"
+
+	pb "{{.ProtoImportPath}}"
+{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
+
+// {{.Service}}Client calls each method of {{.FullService}} on its own
+// Knative Service.  The caller's deadline and trace are forwarded, along with
+// the metadata allowed by client.ForwardHeaders (see New{{.Service}}Client).
+type {{.Service}}Client struct {
+{{range .Methods}}	{{.Field}} pb.{{$.Service}}Client
+{{end}}}
+
+var _ pb.{{.Service}}Client = (*{{.Service}}Client)(nil)
+
+// New{{.Service}}Client connects to the methods of {{.FullService}}.
+// Connections are established lazily, so this may be called from Init.
+func New{{.Service}}Client(ctx context.Context, opts ...grpc.DialOption) (*{{.Service}}Client, error) {
+	c := &{{.Service}}Client{}
+	var conns []*grpc.ClientConn
+	// fail closes the connections dialed before err.
+	fail := func(err error) (*{{.Service}}Client, error) {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return nil, err
+	}
+{{range .Methods}}
+	{{.Field}}, err := client.Dial(ctx, "{{.ServiceName}}", "{{$.Namespace}}", opts...)
+	if err != nil {
+		return fail(err)
+	}
+	conns = append(conns, {{.Field}})
+	c.{{.Field}} = pb.New{{$.Service}}Client({{.Field}})
+{{end}}
+	return c, nil
+}
+{{range .Methods}}
+// {{.Method}} calls {{.Method}} on the {{.ServiceName}} Knative Service.
+{{if .ClientStreaming}}func (c *{{$.Service}}Client) {{.Method}}(ctx context.Context, opts ...grpc.CallOption) (pb.{{$.Service}}_{{.Method}}Client, error) {
+	return c.{{.Field}}.{{.Method}}(ctx, opts...)
+}
+{{else if .ServerStreaming}}func (c *{{$.Service}}Client) {{.Method}}(ctx context.Context, in *{{.RequestType}}, opts ...grpc.CallOption) (pb.{{$.Service}}_{{.Method}}Client, error) {
+	return c.{{.Field}}.{{.Method}}(ctx, in, opts...)
+}
+{{else}}func (c *{{$.Service}}Client) {{.Method}}(ctx context.Context, in *{{.RequestType}}, opts ...grpc.CallOption) (*{{.ResponseType}}, error) {
+	return c.{{.Field}}.{{.Method}}(ctx, in, opts...)
+}
+{{end}}{{end}}
+type {{.Key}} struct{}
+
+var (
+	default{{.Service}}Mu     sync.Mutex
+	default{{.Service}}Client pb.{{.Service}}Client
+)
+
+// With{{.Service}}Client returns a context carrying the given client, which
+// {{.Service}}FromContext returns in place of the default (e.g. a fake).
+func With{{.Service}}Client(ctx context.Context, c pb.{{.Service}}Client) context.Context {
+	return context.WithValue(ctx, {{.Key}}{}, c)
+}
+
+// {{.Service}}FromContext returns the client carried by ctx, or else a
+// process-wide {{.Service}}Client that is created on first use.  Creating it
+// is retried on each call until it succeeds.
+func {{.Service}}FromContext(ctx context.Context) (pb.{{.Service}}Client, error) {
+	if c, ok := ctx.Value({{.Key}}{}).(pb.{{.Service}}Client); ok {
+		return c, nil
+	}
+	default{{.Service}}Mu.Lock()
+	defer default{{.Service}}Mu.Unlock()
+	if default{{.Service}}Client == nil {
+		c, err := New{{.Service}}Client(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to {{.FullService}}: %v", err)
+		}
+		default{{.Service}}Client = c
+	}
+	return default{{.Service}}Client, nil
+}
+`
+)
@@ -0,0 +1,300 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	korpc "github.com/mattmoor/korpc/include"
+)
+
+// compiled returns what protoc passes korpc for a service whose methods
+// exercise the ways in which the generated files name the types of others:
+// Watch responds with google.protobuf.Empty and has its stream managed, Chat
+// has stream_style: STREAM, and Unary is implemented by a main.go that
+// imports the proto package under another name (see implemented).
+func compiled(t *testing.T) *plugin_go.CodeGeneratorRequest {
+	t.Helper()
+	withOptions := func(o *korpc.Options) *descriptor.MethodOptions {
+		opts := &descriptor.MethodOptions{}
+		if err := proto.SetExtension(opts, korpc.E_Options, o); err != nil {
+			t.Fatalf("SetExtension() = %v", err)
+		}
+		return opts
+	}
+	sdp := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("SampleService"),
+		Method: []*descriptor.MethodDescriptorProto{{
+			Name:       proto.String("Unary"),
+			InputType:  proto.String(".sample.Request"),
+			OutputType: proto.String(".sample.Response"),
+		}, {
+			Name:            proto.String("Watch"),
+			InputType:       proto.String(".sample.Request"),
+			OutputType:      proto.String(".google.protobuf.Empty"),
+			ServerStreaming: proto.Bool(true),
+			Options: withOptions(&korpc.Options{
+				Stream: &korpc.Stream{HeartbeatSeconds: 30, MaxDurationSeconds: 240},
+			}),
+		}, {
+			Name:            proto.String("Chat"),
+			InputType:       proto.String(".sample.Request"),
+			OutputType:      proto.String(".sample.Response"),
+			ClientStreaming: proto.Bool(true),
+			ServerStreaming: proto.Bool(true),
+			Options:         withOptions(&korpc.Options{StreamStyle: korpc.Options_STREAM}),
+		}},
+	}
+	empty := protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto)
+	fd := &descriptor.FileDescriptorProto{
+		Name:       proto.String("service.proto"),
+		Package:    proto.String("sample"),
+		Dependency: []string{empty.GetName()},
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("Request")},
+			{Name: proto.String("Response")},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{sdp},
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("example.com/sample/gen/proto;sample"),
+		},
+		Syntax: proto.String("proto3"),
+	}
+	return &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptor.FileDescriptorProto{empty, fd},
+	}
+}
+
+// implemented is the main.go of Unary, which names the proto package
+// samplepb rather than pb.
+const implemented = `package unary
+
+import (
+	"context"
+
+	samplepb "example.com/sample/gen/proto"
+)
+
+func Impl(ctx context.Context, req *samplepb.Request) (*samplepb.Response, error) {
+	return &samplepb.Response{}, nil
+}
+`
+
+// grpcStub declares what protoc-gen-go's grpc plugin would for the service,
+// with just enough bodies to compile, since nothing runs it.
+const grpcStub = `package sample
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var _ emptypb.Empty
+
+type {{.Name}}Client interface {
+{{- range .Methods}}
+	{{.Name}}(ctx context.Context, {{if not .ClientStreaming}}in *{{.In}}, {{end}}opts ...grpc.CallOption) ({{if or .ClientStreaming .ServerStreaming}}{{$.Name}}_{{.Name}}Client{{else}}*{{.Out}}{{end}}, error)
+{{- end}}
+}
+
+func New{{.Name}}Client(cc grpc.ClientConnInterface) {{.Name}}Client {
+	return nil
+}
+{{range .Methods}}{{if or .ClientStreaming .ServerStreaming}}
+type {{$.Name}}_{{.Name}}Client interface {
+	{{- if .ClientStreaming}}
+	Send(*{{.In}}) error
+	{{- end}}
+	{{- if .ServerStreaming}}
+	Recv() (*{{.Out}}, error)
+	{{- else}}
+	CloseAndRecv() (*{{.Out}}, error)
+	{{- end}}
+	grpc.ClientStream
+}
+{{end}}{{end}}
+type {{.Name}}Server interface {
+{{- range .Methods}}
+	{{- if or .ClientStreaming .ServerStreaming}}
+	{{.Name}}({{if not .ClientStreaming}}*{{.In}}, {{end}}{{$.Name}}_{{.Name}}Server) error
+	{{- else}}
+	{{.Name}}(context.Context, *{{.In}}) (*{{.Out}}, error)
+	{{- end}}
+{{- end}}
+}
+
+type Unimplemented{{.Name}}Server struct{}
+{{range .Methods}}
+{{- if or .ClientStreaming .ServerStreaming}}
+func (*Unimplemented{{$.Name}}Server) {{.Name}}({{if not .ClientStreaming}}*{{.In}}, {{end}}{{$.Name}}_{{.Name}}Server) error {
+	return status.Errorf(codes.Unimplemented, "method {{.Name}} not implemented")
+}
+{{- else}}
+func (*Unimplemented{{$.Name}}Server) {{.Name}}(context.Context, *{{.In}}) (*{{.Out}}, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method {{.Name}} not implemented")
+}
+{{- end}}
+{{end}}
+func Register{{.Name}}Server(s grpc.ServiceRegistrar, srv {{.Name}}Server) {}
+{{range .Methods}}{{if or .ClientStreaming .ServerStreaming}}
+type {{$.Name}}_{{.Name}}Server interface {
+	{{- if .ServerStreaming}}
+	Send(*{{.Out}}) error
+	{{- else}}
+	SendAndClose(*{{.Out}}) error
+	{{- end}}
+	{{- if .ClientStreaming}}
+	Recv() (*{{.In}}, error)
+	{{- end}}
+	grpc.ServerStream
+}
+{{end}}{{end}}`
+
+// protoFiles returns the Go files of the proto package that request
+// generates, by their names: the messages, as protoc-gen-go generates them,
+// and a stub of its services.
+func protoFiles(t *testing.T, request *plugin_go.CodeGeneratorRequest) map[string]string {
+	t.Helper()
+	req := proto.Clone(request).(*plugin_go.CodeGeneratorRequest)
+	req.Parameter = proto.String("paths=source_relative")
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			internal_gengo.GenerateFile(gen, f)
+		}
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("Response() = %s", resp.GetError())
+	}
+	files := make(map[string]string)
+	for _, f := range resp.File {
+		files[f.GetName()] = f.GetContent()
+	}
+
+	tmpl := template.Must(template.New("grpc").Parse(grpcStub))
+	goType := func(name string) string {
+		if name == ".google.protobuf.Empty" {
+			return "emptypb.Empty"
+		}
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+	type method struct {
+		Name, In, Out                    string
+		ClientStreaming, ServerStreaming bool
+	}
+	for _, fd := range request.ProtoFile {
+		if fd.GetName() != request.FileToGenerate[0] {
+			continue
+		}
+		for _, sdp := range fd.Service {
+			var methods []method
+			for _, mdp := range sdp.Method {
+				methods = append(methods, method{
+					Name:            mdp.GetName(),
+					In:              goType(mdp.GetInputType()),
+					Out:             goType(mdp.GetOutputType()),
+					ClientStreaming: mdp.GetClientStreaming(),
+					ServerStreaming: mdp.GetServerStreaming(),
+				})
+			}
+			buf := &bytes.Buffer{}
+			if err := tmpl.Execute(buf, struct {
+				Name    string
+				Methods []method
+			}{sdp.GetName(), methods}); err != nil {
+				t.Fatalf("Execute() = %v", err)
+			}
+			files[strings.ToLower(sdp.GetName())+"_grpc.pb.go"] = buf.String()
+		}
+	}
+	return files
+}
+
+// The files generated in a single pass must compile, along with the methods
+// that are already implemented.
+func TestCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping building the generated files in short mode")
+	}
+	gobin := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if _, err := os.Stat(gobin); err != nil {
+		t.Skipf("Unable to find the go command: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() = %v", err)
+	}
+	korpcRoot := filepath.Join(wd, "..", "..")
+	sum, err := os.ReadFile(filepath.Join(korpcRoot, "go.sum"))
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+
+	request := compiled(t)
+	root := t.TempDir()
+	files := generate(t, root, request, singlePass(false), map[string]string{
+		"pkg/methods/sampleservice/unary/main.go": implemented,
+	})
+	if _, ok := files["pkg/methods/sampleservice/unary/main.go"]; ok {
+		t.Error("The implemented Impl of Unary is rewritten")
+	}
+	for name, content := range protoFiles(t, request) {
+		files[filepath.Join("gen/proto", name)] = content
+	}
+	// Build against this korpc, resolving its dependencies as it does.
+	files["go.mod"] = "module example.com/sample\n\ngo " + strings.TrimPrefix(runtime.Version(), "go") +
+		"\n\nrequire github.com/mattmoor/korpc v0.0.0\n\nreplace github.com/mattmoor/korpc => " + korpcRoot + "\n"
+	files["go.sum"] = string(sum)
+	for name, content := range files {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatalf("MkdirAll() = %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatalf("WriteFile() = %v", err)
+		}
+	}
+
+	cmd := exec.Command(gobin, "vet", "./...")
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet = %v\n%s", err, out)
+	}
+}
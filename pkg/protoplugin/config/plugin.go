@@ -21,16 +21,15 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/golang/protobuf/protoc-gen-go/plugin"
 
-	"github.com/mattmoor/korpc/pkg/install"
+	"github.com/mattmoor/korpc/pkg/comments"
+	"github.com/mattmoor/korpc/pkg/defaults"
 	"github.com/mattmoor/korpc/pkg/naming"
 	"github.com/mattmoor/korpc/pkg/parameter"
 	"github.com/mattmoor/korpc/pkg/protoplugin"
-
-	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
 type plugin struct {
@@ -46,15 +45,11 @@ func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequ
 }
 
 func (p *plugin) doMeta(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
-	files := make([]string, 0, len(request.FileToGenerate))
 	codegen := make(map[string]struct{})
 	for _, file := range request.FileToGenerate {
 		codegen[file] = struct{}{}
-		files = append(files, filepath.Join(stuff.NestingEscape(), file))
 	}
 
-	generateCmds := []string{"package config"}
-
 	var resp plugin_go.CodeGeneratorResponse
 	for _, fd := range request.ProtoFile {
 		if _, ok := codegen[fd.GetName()]; !ok {
@@ -62,44 +57,35 @@ func (p *plugin) doMeta(stuff *parameter.Stuff, request *plugin_go.CodeGenerator
 		}
 		for _, sdp := range fd.Service {
 			for _, mdp := range sdp.Method {
-				binary, args := install.ProtoCCmd(
-					".",
-					install.KORPCPath,
-					parameter.Stuff{
-						Name:            "config",
-						Base:            stuff.Base,
-						GenDir:          stuff.GenDir,
-						MethodsDir:      stuff.MethodsDir,
-						Domain:          stuff.Domain,
-						Namespace:       stuff.Namespace,
-						Service:         sdp.GetName(),
-						Method:          mdp.GetName(),
-						NestedDirectory: stuff.NestedDirectory,
-					},
-					files...,
-				)
-
-				generateCmds = append(generateCmds,
-					fmt.Sprintf("//go:generate %s %s\n", binary, strings.Join(args, " ")))
+				method := parameter.Stuff{
+					Name:            "config",
+					Base:            stuff.Base,
+					GenDir:          stuff.GenDir,
+					MethodsDir:      stuff.MethodsDir,
+					Domain:          stuff.Domain,
+					Namespace:       stuff.Namespace,
+					Templates:       stuff.Templates,
+					Modules:         stuff.Modules,
+					Domains:         stuff.Domains,
+					Includes:        stuff.Includes,
+					Service:         sdp.GetName(),
+					Method:          mdp.GetName(),
+					NestedDirectory: stuff.NestedDirectory,
+				}
+				mresp, err := p.doMethod(&method, request)
+				if err != nil {
+					return nil, err
+				}
+				protoplugin.Nest(&resp, ".", mresp)
 			}
 		}
 	}
-
-	mainName := "korpc.go"
-	mainContent := strings.Join(generateCmds, "\n")
-
-	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
-		Name:    &mainName,
-		Content: &mainContent,
-	})
 	return &resp, nil
 }
 
-var tmpl = template.Must(template.New("service").Parse(serviceTemplate))
-
 func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
-	sdp, mdp := getSDPAndMDP(stuff, request)
-	if sdp == nil || mdp == nil {
+	fd, sdp, mdp := getDescriptors(stuff, request)
+	if fd == nil || sdp == nil || mdp == nil {
 		return nil, fmt.Errorf("Unable to find %s.%s", stuff.Service, stuff.Method)
 	}
 
@@ -110,13 +96,15 @@ func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGenerat
 		GatewayPath: filepath.Join(stuff.Base, stuff.GenDir, "entrypoint",
 			strings.ToLower(sdp.GetName()), strings.ToLower(mdp.GetName())),
 		MethodLower: strings.ToLower(mdp.GetName()),
+		Options:     *defaults.Options(mdp),
+		Description: comments.Description(fd, sdp, mdp),
+		Deprecated:  mdp.GetOptions().GetDeprecated(),
 	}
 
-	addr, err := proto.GetExtension(mdp.Options, korpc.E_Options)
-	if err == nil {
-		opt.Options = *(addr.(*korpc.Options))
+	tmpl, err := templates.Parse(stuff.Templates, templateName)
+	if err != nil {
+		return nil, err
 	}
-
 	mainName := naming.Service(sdp, mdp) + ".yaml"
 	mainContent, err := execToString(tmpl, opt)
 	if err != nil {
@@ -131,7 +119,7 @@ func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGenerat
 	return &resp, nil
 }
 
-func getSDPAndMDP(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*descriptor.ServiceDescriptorProto, *descriptor.MethodDescriptorProto) {
+func getDescriptors(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*descriptor.FileDescriptorProto, *descriptor.ServiceDescriptorProto, *descriptor.MethodDescriptorProto) {
 	codegen := make(map[string]struct{})
 	for _, file := range request.FileToGenerate {
 		codegen[file] = struct{}{}
@@ -143,12 +131,12 @@ func getSDPAndMDP(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorReques
 		for _, sdp := range fd.Service {
 			for _, mdp := range sdp.Method {
 				if sdp.GetName() == stuff.Service && mdp.GetName() == stuff.Method {
-					return sdp, mdp
+					return fd, sdp, mdp
 				}
 			}
 		}
 	}
-	return nil, nil
+	return nil, nil, nil
 }
 
 // execute a template to produce a string.
@@ -16,14 +16,29 @@ package config
 
 import (
 	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
+// templateName is the file from which projects may override serviceTemplate.
+const templateName = "service.yaml.tmpl"
+
+// options is the data with which a method's Knative Service is generated.
 type options struct {
-	Name        string
-	Namespace   string
+	// Name is the name of the Knative Service.
+	Name string
+	// Namespace is the namespace into which it is deployed.
+	Namespace string
+	// GatewayPath is the import path of the method's entrypoint, which ko
+	// builds into the image.
 	GatewayPath string
+	// MethodLower is the lowercased name of the RPC.
 	MethodLower string
-	Options     korpc.Options
+	// Options are the method's korpc.options, with defaults filled in.
+	Options korpc.Options
+	// Description holds the RPC's comments from its proto, on one line.
+	Description string
+	// Deprecated is set when the RPC is marked deprecated in its proto.
+	Deprecated bool
 }
 
 const (
@@ -31,9 +46,17 @@ const (
 kind: Service
 metadata:
   name: {{$.Name}}
-  namespace: {{$.Namespace}}
+  namespace: {{$.Namespace}}{{if or $.Description $.Deprecated}}
+  annotations:{{if $.Description}}
+    korpc.mattmoor.io/description: {{printf "%q" $.Description}}{{end}}{{if $.Deprecated}}
+    korpc.mattmoor.io/deprecated: "true"{{end}}{{end}}
 spec:
-  template:
+  template:{{with $.Options.Metrics}}
+    metadata:
+      annotations:
+        prometheus.io/scrape: "true"
+        prometheus.io/port: "8080"
+        prometheus.io/path: {{.Path}}{{end}}
     spec:
       {{if ne "" $.Options.ServiceAccount}}serviceAccountName: {{$.Options.ServiceAccount}}{{end}}
       {{if ne 0 $.Options.ContainerConcurrency}}containerConcurrency: {{$.Options.ContainerConcurrency}}{{end}}
@@ -59,3 +82,7 @@ spec:
             {{$key}}: {{$value}}{{end}}
 `
 )
+
+func init() {
+	templates.Register(templateName, serviceTemplate)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entrypoint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hooks reports which of the optional Init and Close lifecycle functions
+// are declared by the method package in dir, failing if either is declared
+// with the wrong signature.  A package that does not exist
+// yet (e.g. before the scaffold has been generated) declares neither.
+func hooks(dir string) (hasInit bool, hasClose bool, err error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+
+	for _, pkg := range pkgs {
+		for name, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Recv != nil {
+					continue
+				}
+				switch fd.Name.Name {
+				case "Init", "Close":
+				default:
+					continue
+				}
+				// Both hooks have the signature: func(context.Context) error
+				if !isHook(f, fd.Type) {
+					return false, false, fmt.Errorf("%s: %s must have the signature func(context.Context) error",
+						name, fd.Name.Name)
+				}
+				switch fd.Name.Name {
+				case "Init":
+					hasInit = true
+				case "Close":
+					hasClose = true
+				}
+			}
+		}
+	}
+	return hasInit, hasClose, nil
+}
+
+// isHook reports whether ft is func(context.Context) error, with context
+// imported under whatever name f gives it.
+func isHook(f *ast.File, ft *ast.FuncType) bool {
+	if ft.Params.NumFields() != 1 || ft.Results.NumFields() != 1 {
+		return false
+	}
+	if id, ok := ft.Results.List[0].Type.(*ast.Ident); !ok || id.Name != "error" {
+		return false
+	}
+	sel, ok := ft.Params.List[0].Type.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for _, imp := range f.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path != "context" {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name == pkg.Name
+		}
+		return pkg.Name == "context"
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entrypoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantInit  bool
+		wantClose bool
+		wantErr   bool
+	}{{
+		name: "neither",
+		src:  "package impl\n",
+	}, {
+		name: "both",
+		src: `package impl
+import "context"
+func Init(ctx context.Context) error { return nil }
+func Close(context.Context) error { return nil }
+`,
+		wantInit:  true,
+		wantClose: true,
+	}, {
+		name: "renamed context import",
+		src: `package impl
+import stdctx "context"
+func Init(ctx stdctx.Context) error { return nil }
+`,
+		wantInit: true,
+	}, {
+		name: "methods are not hooks",
+		src: `package impl
+type conn struct{}
+func (conn) Close() {}
+`,
+	}, {
+		name: "wrong parameter",
+		src: `package impl
+func Init(s string) error { return nil }
+`,
+		wantErr: true,
+	}, {
+		name: "another Context",
+		src: `package impl
+import context "example.com/notcontext"
+func Init(ctx context.Context) error { return nil }
+`,
+		wantErr: true,
+	}, {
+		name: "wrong result",
+		src: `package impl
+import "context"
+func Close(ctx context.Context) {}
+`,
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hooks")
+			if err != nil {
+				t.Fatalf("TempDir() = %v", err)
+			}
+			defer os.RemoveAll(dir)
+			if err := ioutil.WriteFile(filepath.Join(dir, "impl.go"), []byte(test.src), 0644); err != nil {
+				t.Fatalf("WriteFile() = %v", err)
+			}
+
+			hasInit, hasClose, err := hooks(dir)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("hooks() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if hasInit != test.wantInit || hasClose != test.wantClose {
+				t.Errorf("hooks() = %v, %v, wanted %v, %v", hasInit, hasClose, test.wantInit, test.wantClose)
+			}
+		})
+	}
+
+	t.Run("missing package", func(t *testing.T) {
+		if _, _, err := hooks(filepath.Join(os.TempDir(), "does-not-exist")); err != nil {
+			t.Errorf("hooks() = %v", err)
+		}
+	})
+}
@@ -17,6 +17,11 @@ package entrypoint
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -24,34 +29,47 @@ import (
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/golang/protobuf/protoc-gen-go/plugin"
 
-	"github.com/mattmoor/korpc/pkg/install"
+	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/comments"
+	"github.com/mattmoor/korpc/pkg/defaults"
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/modules"
+	"github.com/mattmoor/korpc/pkg/naming"
 	"github.com/mattmoor/korpc/pkg/parameter"
 	"github.com/mattmoor/korpc/pkg/protoplugin"
 	"github.com/mattmoor/korpc/pkg/protoplugin/scaffold"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
 type plugin struct {
 }
 
+// compressors maps the names of the compressors that methods may register to
+// the packages that register them.
+var compressors = map[string]string{
+	"gzip": "google.golang.org/grpc/encoding/gzip",
+}
+
 var _ protoplugin.Interface = (*plugin)(nil)
 
 func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
 	if stuff.Service != "" || stuff.Method != "" {
-		return p.doMethod(stuff, request)
+		// Generating a single method, we are run from the directory holding
+		// the entrypoints.
+		root := (&parameter.Stuff{
+			NestedDirectory: filepath.Dir(filepath.Dir(stuff.NestedDirectory)),
+		}).NestingEscape()
+		return p.doMethod(stuff, request, root)
 	}
 	return p.doMeta(stuff, request)
 }
 
 func (p *plugin) doMeta(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
-	files := make([]string, 0, len(request.FileToGenerate))
 	codegen := make(map[string]struct{})
 	for _, file := range request.FileToGenerate {
 		codegen[file] = struct{}{}
-		files = append(files, filepath.Join(stuff.NestingEscape(), file))
 	}
 
-	generateCmds := []string{"package entrypoint"}
-
 	var resp plugin_go.CodeGeneratorResponse
 	for _, fd := range request.ProtoFile {
 		if _, ok := codegen[fd.GetName()]; !ok {
@@ -61,41 +79,36 @@ func (p *plugin) doMeta(stuff *parameter.Stuff, request *plugin_go.CodeGenerator
 			for _, mdp := range sdp.Method {
 				dir := filepath.Join(strings.ToLower(sdp.GetName()),
 					strings.ToLower(mdp.GetName()))
-				binary, args := install.ProtoCCmd(
-					dir,
-					install.KORPCPath,
-					parameter.Stuff{
-						Name:            "entrypoint",
-						Base:            stuff.Base,
-						GenDir:          stuff.GenDir,
-						MethodsDir:      stuff.MethodsDir,
-						Domain:          stuff.Domain,
-						Namespace:       stuff.Namespace,
-						Service:         sdp.GetName(),
-						Method:          mdp.GetName(),
-						NestedDirectory: filepath.Join(stuff.NestedDirectory, dir),
-					},
-					files...,
-				)
-
-				generateCmds = append(generateCmds,
-					fmt.Sprintf("//go:generate mkdir -p %s", dir),
-					fmt.Sprintf("//go:generate %s %s\n", binary, strings.Join(args, " ")))
+				method := parameter.Stuff{
+					Name:            "entrypoint",
+					Base:            stuff.Base,
+					GenDir:          stuff.GenDir,
+					MethodsDir:      stuff.MethodsDir,
+					Domain:          stuff.Domain,
+					Namespace:       stuff.Namespace,
+					Templates:       stuff.Templates,
+					Modules:         stuff.Modules,
+					Domains:         stuff.Domains,
+					Includes:        stuff.Includes,
+					Service:         sdp.GetName(),
+					Method:          mdp.GetName(),
+					NestedDirectory: filepath.Join(stuff.NestedDirectory, dir),
+				}
+				// We are run from the root of the repository.
+				mresp, err := p.doMethod(&method, request, ".")
+				if err != nil {
+					return nil, err
+				}
+				protoplugin.Nest(&resp, dir, mresp)
 			}
 		}
 	}
-
-	mainName := "korpc.go"
-	mainContent := strings.Join(generateCmds, "\n")
-
-	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
-		Name:    &mainName,
-		Content: &mainContent,
-	})
 	return &resp, nil
 }
 
-func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+// doMethod generates the method's entrypoint, given the path from the
+// directory from which we are run to the root of the repository.
+func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest, root string) (*plugin_go.CodeGeneratorResponse, error) {
 	codegen := make(map[string]struct{})
 	for _, file := range request.FileToGenerate {
 		codegen[file] = struct{}{}
@@ -104,57 +117,268 @@ func (p *plugin) doMethod(stuff *parameter.Stuff, request *plugin_go.CodeGenerat
 	opt := &options{
 		ImplImportPath: filepath.Join(stuff.Base, stuff.MethodsDir,
 			strings.ToLower(stuff.Service), strings.ToLower(stuff.Method)),
+		AdapterImportPath: filepath.Join(stuff.Base, stuff.GenDir, "entrypoint",
+			strings.ToLower(stuff.Service), strings.ToLower(stuff.Method), "adapter"),
 		Service: stuff.Service,
 	}
 
+	// Find the method package relative to the root of the repository.
+	implDir := filepath.Join(root, stuff.MethodsDir,
+		strings.ToLower(stuff.Service), strings.ToLower(stuff.Method))
+
+	var err error
 	var resp plugin_go.CodeGeneratorResponse
+	var streamContent string
 	for _, fd := range request.ProtoFile {
 		if _, ok := codegen[fd.GetName()]; !ok {
 			continue
 		}
 		for _, sdp := range fd.Service {
 			for _, mdp := range sdp.Method {
+				if sdp.GetName() == stuff.Service && mdp.GetName() != stuff.Method {
+					opt.UnimplementedMethods = append(opt.UnimplementedMethods, mdp.GetName())
+				}
 				if sdp.GetName() == stuff.Service && mdp.GetName() == stuff.Method {
-					opt.ProtoImportPath = filepath.Join(stuff.Base, stuff.GenDir, "proto",
-						// protoc-gen-go includes directory names
-						filepath.Dir(fd.GetName()))
-					var err error
-					opt.Implementation, err = impl(sdp, mdp)
+					r := gotypes.New(stuff, request, fd)
+					opt.ProtoImportPath = gotypes.ImportPath(stuff, fd)
+					opt.Name = naming.Service(sdp, mdp)
+					opt.FullService = fmt.Sprintf("%s.%s", fd.GetPackage(), sdp.GetName())
+					opt.Method = mdp.GetName()
+					opt.Options = *defaults.Options(mdp)
+					if path := opt.Options.ImplImportPath; path != "" {
+						// The package may live anywhere (e.g. in another module),
+						// so let the go tool find it.
+						pkg, err := build.Import(path, root, build.FindOnly)
+						if err != nil {
+							return nil, fmt.Errorf("%s.%s: unable to find impl_import_path: %v", sdp.GetName(), mdp.GetName(), err)
+						}
+						opt.ImplImportPath, implDir = path, pkg.Dir
+					}
+					opt.ResponseType, err = r.GoType(mdp.GetOutputType())
 					if err != nil {
 						return nil, err
 					}
+					// Nothing else has been resolved yet, so these are just
+					// what names the response.
+					opt.ResponseImports = append([]gotypes.Import(nil), r.Imports()...)
+					if st := opt.Options.Stream; st != nil {
+						if !mdp.GetClientStreaming() && !mdp.GetServerStreaming() {
+							return nil, fmt.Errorf("%s.%s: stream options require a streaming method", sdp.GetName(), mdp.GetName())
+						}
+						if st.HeartbeatSeconds != 0 && !mdp.GetServerStreaming() {
+							return nil, fmt.Errorf("%s.%s: heartbeats require a server streaming method", sdp.GetName(), mdp.GetName())
+						}
+					}
+					if opt.Options.StreamStyle != korpc.Options_CHANNELS && !mdp.GetClientStreaming() && !mdp.GetServerStreaming() {
+						return nil, fmt.Errorf("%s.%s: stream_style requires a streaming method", sdp.GetName(), mdp.GetName())
+					}
+					if opt.Options.Cache != nil {
+						if mdp.GetClientStreaming() || mdp.GetServerStreaming() {
+							return nil, fmt.Errorf("%s.%s: only unary methods may be cached", sdp.GetName(), mdp.GetName())
+						}
+						if mdp.GetOptions().GetIdempotencyLevel() != descriptor.MethodOptions_NO_SIDE_EFFECTS {
+							return nil, fmt.Errorf("%s.%s: only methods with idempotency_level = NO_SIDE_EFFECTS may be cached", sdp.GetName(), mdp.GetName())
+						}
+					}
+					if opt.Options.GetRateLimit().GetKeyClaim() != "" && opt.Options.Auth == nil {
+						return nil, fmt.Errorf("%s.%s: rate_limit.key_claim requires auth", sdp.GetName(), mdp.GetName())
+					}
+					for _, name := range opt.Options.GetServer().GetCompressors() {
+						path, ok := compressors[name]
+						if !ok {
+							return nil, fmt.Errorf("%s.%s: unsupported compressor %q", sdp.GetName(), mdp.GetName(), name)
+						}
+						opt.Compressors = append(opt.Compressors, path)
+					}
+					opt.Implementation, err = impl(sdp, mdp, r, comments.Doc(fd, sdp, mdp))
+					if err != nil {
+						return nil, err
+					}
+					// The types of streaming methods needn't appear in the
+					// signature of the handler.
+					opt.Imports, err = used(opt.Implementation, r.Imports())
+					if err != nil {
+						return nil, err
+					}
+					if opt.Options.StreamStyle == korpc.Options_STREAM {
+						if streamContent, err = streamServer(stuff, request, fd, sdp, mdp); err != nil {
+							return nil, err
+						}
+					}
 				}
 			}
 		}
 	}
 
+	opt.HasInit, opt.HasClose, err = hooks(implDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := templates.Parse(stuff.Templates, templateName)
+	if err != nil {
+		return nil, err
+	}
 	mainName := "main.go"
 	mainContent, err := execToString(tmpl, opt)
 	if err != nil {
 		return nil, err
 	}
 
+	// The adapter lives in its own package, so that the tests scaffolded
+	// alongside impl.Impl can serve it.
+	adapterTmpl, err := templates.Parse(stuff.Templates, adapterTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	adapterName := filepath.Join("adapter", "adapter.go")
+	adapterContent, err := execToString(adapterTmpl, opt)
+	if err != nil {
+		return nil, err
+	}
+
 	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
 		Name:    &mainName,
 		Content: &mainContent,
+	}, &plugin_go.CodeGeneratorResponse_File{
+		Name:    &adapterName,
+		Content: &adapterContent,
 	})
+	if streamContent != "" {
+		streamName := filepath.Join("stream", "stream.go")
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &streamName,
+			Content: &streamContent,
+		})
+	}
+
+	// The go.mod is only created once, after which `go mod tidy` maintains
+	// its requirements.
+	if stuff.Modules && !exists(filepath.Join(root, stuff.NestedDirectory, "go.mod")) {
+		modName := "go.mod"
+		modContent, err := goMod(stuff, root, opt.Options.ImplImportPath == "")
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &modName,
+			Content: &modContent,
+		})
+	}
 	return &resp, nil
 }
 
-func impl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) (string, error) {
+// goMod renders the go.mod of the entrypoint's module, which requires the
+// generated proto package and, unless it is implemented elsewhere, the method
+// from the directories of their modules.  Unlike the go.mod, root is relative
+// to the directory from which we are run.
+func goMod(stuff *parameter.Stuff, root string, local bool) (string, error) {
+	goVersion, err := modules.GoVersion(root)
+	if err != nil {
+		return "", err
+	}
+	replaces := []modules.Replace{{
+		Path: modules.Path(stuff, modules.ProtoDir(stuff)),
+		Dir:  filepath.Join(stuff.NestingEscape(), modules.ProtoDir(stuff)),
+	}}
+	if local {
+		dir := modules.MethodDir(stuff, stuff.Service, stuff.Method)
+		replaces = append(replaces, modules.Replace{
+			Path: modules.Path(stuff, dir),
+			Dir:  filepath.Join(stuff.NestingEscape(), dir),
+		})
+	}
+	return modules.GoMod(modules.Path(stuff, modules.EntrypointDir(stuff, stuff.Service, stuff.Method)), goVersion, replaces...)
+}
+
+// exists reports whether the file exists.
+func exists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// used returns those of the imports that the source of the function fn
+// refers to.
+func used(fn string, imports []gotypes.Import) ([]gotypes.Import, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package adapter\n"+fn, 0)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{})
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				names[id.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+	var result []gotypes.Import
+	for _, imp := range imports {
+		if _, ok := names[imp.Alias]; ok {
+			result = append(result, imp)
+		}
+	}
+	return result, nil
+}
+
+// streamServer renders the package declaring the stream that Impl is passed
+// under stream_style: STREAM.  It has a resolver of its own, so that it only
+// imports what names the types it streams.
+func streamServer(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest, fd *descriptor.FileDescriptorProto, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) (string, error) {
+	r := gotypes.New(stuff, request, fd)
+	opt := &streamOptions{
+		Service:         sdp.GetName(),
+		Method:          mdp.GetName(),
+		ProtoImportPath: gotypes.ImportPath(stuff, fd),
+	}
+	var err error
+	if mdp.GetClientStreaming() {
+		if opt.RequestType, err = r.GoType(mdp.GetInputType()); err != nil {
+			return "", err
+		}
+	}
+	if mdp.GetServerStreaming() {
+		if opt.ResponseType, err = r.GoType(mdp.GetOutputType()); err != nil {
+			return "", err
+		}
+	}
+	opt.UsesProto = strings.HasPrefix(opt.RequestType, "pb.") || strings.HasPrefix(opt.ResponseType, "pb.")
+	opt.Imports = r.Imports()
+	return execToString(streamTmpl, opt)
+}
+
+func impl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto, r *gotypes.Resolver, doc string) (string, error) {
+	requestType, err := r.GoType(mdp.GetInputType())
+	if err != nil {
+		return "", err
+	}
+	responseType, err := r.GoType(mdp.GetOutputType())
+	if err != nil {
+		return "", err
+	}
+
 	opt := map[string]string{
 		"Service":      sdp.GetName(),
 		"Method":       mdp.GetName(),
 		"Name":         mdp.GetName(),
-		"RequestType":  extract(mdp.GetInputType()),
-		"ResponseType": extract(mdp.GetOutputType()),
-		"Receiver":     "(s *server) ",
+		"RequestType":  requestType,
+		"ResponseType": responseType,
+		"Receiver":     "(s *Server) ",
+		"Doc":          doc,
 	}
+	stream := defaults.Options(mdp).GetStreamStyle() == korpc.Options_STREAM
 	switch {
+	case mdp.GetServerStreaming() && mdp.GetClientStreaming() && stream:
+		return execToString(streamInOutStreamMethod, opt)
 	case mdp.GetServerStreaming() && mdp.GetClientStreaming():
 		return execToString(streamInOutMethod, opt)
+	case mdp.GetClientStreaming() && stream:
+		return execToString(streamInStreamMethod, opt)
 	case mdp.GetClientStreaming():
 		return execToString(streamInMethod, opt)
+	case mdp.GetServerStreaming() && stream:
+		return execToString(streamOutStreamMethod, opt)
 	case mdp.GetServerStreaming():
 		return execToString(streamOutMethod, opt)
 	default:
@@ -163,12 +387,6 @@ func impl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescript
 	}
 }
 
-// proto types come through as `.package.TypeName` so extract the last portion.
-func extract(t string) string {
-	parts := strings.Split(t, ".")
-	return parts[len(parts)-1]
-}
-
 // execute a template to produce a string.
 func execToString(t *template.Template, opt interface{}) (string, error) {
 	buf := &bytes.Buffer{}
@@ -16,14 +16,77 @@ package entrypoint
 
 import (
 	"text/template"
+
+	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/templates"
+)
+
+const (
+	// templateName is the file from which projects may override entrypointTemplate.
+	templateName = "entrypoint.go.tmpl"
+
+	// adapterTemplateName is the file from which projects may override
+	// adapterTemplate.
+	adapterTemplateName = "adapter.go.tmpl"
 )
 
+// streamOptions is the data with which the stream package of a method with
+// stream_style: STREAM is generated.
+type streamOptions struct {
+	Service string
+	Method  string
+	// ProtoImportPath is the import path of the generated proto package,
+	// which is only imported when UsesProto.
+	ProtoImportPath string
+	UsesProto       bool
+	// RequestType is set when the client streams, and ResponseType when the
+	// server streams, and Imports are needed to name them.
+	RequestType  string
+	ResponseType string
+	Imports      []gotypes.Import
+}
+
+// options is the data with which the main.go and adapter of a method's
+// entrypoint are generated.
 type options struct {
-	ProtoImportPath      string
-	ImplImportPath       string
-	Service              string
-	Implementation       string
+	// Name is the name of the method's Knative Service.
+	Name string
+	// ProtoImportPath is the import path of the generated proto package.
+	ProtoImportPath string
+	// ImplImportPath is the import path of the package that implements the method.
+	ImplImportPath string
+	// AdapterImportPath is the import path of the package that adapts Impl
+	// to the gRPC server interface.
+	AdapterImportPath string
+	// Service is the name of the proto service, and FullService is qualified
+	// by the proto package.
+	Service     string
+	FullService string
+	// Method is the name of the RPC.
+	Method string
+	// Implementation is the source of the adapter.Server method that serves
+	// the RPC by calling Impl.
+	Implementation string
+	// UnimplementedMethods are the service's other RPCs, which adapter.Server
+	// answers with UNIMPLEMENTED (through pb.Unimplemented<Service>Server),
+	// since each has an entrypoint of its own.
 	UnimplementedMethods []string
+	// Options are the method's korpc.options, with defaults filled in.
+	Options korpc.Options
+	// HasInit and HasClose are set when the method's package declares Init
+	// and Close.
+	HasInit  bool
+	HasClose bool
+	// Compressors are the import paths of the compressors to register.
+	Compressors []string
+	// ResponseType is the Go type of the response, which ResponseImports
+	// are needed to name.
+	ResponseType    string
+	ResponseImports []gotypes.Import
+	// Imports are those that Implementation needs to name the request and
+	// response types, when it names them at all.
+	Imports []gotypes.Import
 }
 
 const (
@@ -31,10 +94,11 @@ const (
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/context"
@@ -42,18 +106,27 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
-	"go.opencensus.io/plugin/ocgrpc"
+{{with .Options.Server}}{{if .Keepalive}}	"google.golang.org/grpc/keepalive"
+{{end}}{{end}}{{range .Compressors}}	_ "{{.}}"
+{{end}}	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/stats/view"
 
+{{if .Options.Auth}}	"github.com/mattmoor/korpc/pkg/runtime/auth"
+{{end}}{{if .Options.Cache}}	"github.com/mattmoor/korpc/pkg/runtime/cache"
+{{end}}	"github.com/mattmoor/korpc/pkg/runtime/deadline"
+	"github.com/mattmoor/korpc/pkg/runtime/errors"
+	"github.com/mattmoor/korpc/pkg/runtime/logging"
+{{if .Options.Metrics}}	"github.com/mattmoor/korpc/pkg/runtime/metrics"
+{{end}}{{if .Options.RateLimit}}	"github.com/mattmoor/korpc/pkg/runtime/ratelimit"
+{{end}}{{if .Options.Stream}}	"github.com/mattmoor/korpc/pkg/runtime/streaming"
+{{end}}	"github.com/mattmoor/korpc/pkg/runtime/telemetry"
+{{if not .Options.SkipValidation}}	"github.com/mattmoor/korpc/pkg/runtime/validate"
+{{end}}
+	"{{.AdapterImportPath}}"
 	pb "{{.ProtoImportPath}}"
-	impl "{{.ImplImportPath}}"
-)
-
-type server struct {
-  pb.Unimplemented{{.Service}}Server
-}
-
-{{.Implementation}}
+{{if or .HasInit .HasClose}}	impl "{{.ImplImportPath}}"
+{{end}}{{if .Options.Stream}}{{range .ResponseImports}}	{{.Alias}} "{{.Path}}"
+{{end}}{{end}})
 
 func main() {
 	if len(os.Args) > 1 && os.Args[1] == "probe" {
@@ -61,30 +134,170 @@ func main() {
 		return
 	}
 
-	// Prior to this, exporters should be registered via:
-	// func init() {
-	//    view.RegisterExporter(...)
-	// }
-
 	// Register the views to collect server request count.
 	if err := view.Register(ocgrpc.DefaultServerViews...); err != nil {
 		log.Fatal(err)
 	}
 
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%s", os.Getenv("PORT")))
+	// Set up OpenTelemetry, which continues the caller's trace and places the
+	// server span in the context passed to impl.Impl.
+	shutdown, err := telemetry.Setup(context.Background(), telemetry.Config{
+		ServiceName: "{{.Name}}",
+		Endpoint:    "{{.Options.Telemetry.Endpoint}}",
+		Insecure:    {{.Options.Telemetry.Insecure}},
+		SampleRatio: {{.Options.Telemetry.SampleRatio}},
+	})
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("failed to set up telemetry: %v", err)
 	}
+	defer shutdown(context.Background())
 
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
+		grpc.StatsHandler(telemetry.ServerHandler()),
+	}
+{{with .Options.Server}}{{if .MaxReceiveMessageBytes}}	opts = append(opts, grpc.MaxRecvMsgSize({{.MaxReceiveMessageBytes}}))
+{{end}}{{if .MaxSendMessageBytes}}	opts = append(opts, grpc.MaxSendMsgSize({{.MaxSendMessageBytes}}))
+{{end}}{{if .MaxConcurrentStreams}}	opts = append(opts, grpc.MaxConcurrentStreams({{.MaxConcurrentStreams}}))
+{{end}}{{with .Keepalive}}	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		Time:                  {{.TimeSeconds}} * time.Second,
+		Timeout:               {{.TimeoutSeconds}} * time.Second,
+		MaxConnectionIdle:     {{.MaxConnectionIdleSeconds}} * time.Second,
+		MaxConnectionAge:      {{.MaxConnectionAgeSeconds}} * time.Second,
+		MaxConnectionAgeGrace: {{.MaxConnectionAgeGraceSeconds}} * time.Second,
+	}))
+{{if or .MinTimeSeconds .PermitWithoutStream}}	opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             {{.MinTimeSeconds}} * time.Second,
+		PermitWithoutStream: {{.PermitWithoutStream}},
+	}))
+{{end}}{{end}}{{end}}
+	// Place a request-scoped logger into the context passed to impl.Impl.
+	logger, err := logging.New(logging.Config{
+		Service: "{{.FullService}}",
+		Method:  "{{.Method}}",
+		Level:   "{{.Options.Logging.Level}}",
+	})
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(logger, {{.Options.Logging.AccessLog}})),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(logger, {{.Options.Logging.AccessLog}})))
+
+	// Bound each request by the method's timeout, so that impl.Impl stops when
+	// the queue-proxy gives up on the request.
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(deadline.UnaryServerInterceptor({{.Options.TimeoutSeconds}} * time.Second)),
+		grpc.ChainStreamInterceptor(deadline.StreamServerInterceptor({{.Options.TimeoutSeconds}} * time.Second)))
+{{with .Options.Metrics}}
+	// Collect the metrics that are served to Prometheus.
+	if err := metrics.Register(); err != nil {
+		log.Fatal(err)
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor()))
+{{end}}{{with .Options.Auth}}
+	// Require callers to present a bearer token, and place its claims into
+	// the context passed to impl.Impl.
+	authenticator, err := auth.New(auth.Config{
+		Issuer:         {{printf "%q" .Issuer}},
+		Audiences:      []string{ {{- range $i, $a := .Audiences}}{{if $i}}, {{end}}{{printf "%q" $a}}{{end -}} },
+		JWKSFile:       {{printf "%q" .JwksFile}},
+		JWKSURL:        {{printf "%q" .JwksUrl}},
+		RequiredScopes: []string{ {{- range $i, $s := .RequiredScopes}}{{if $i}}, {{end}}{{printf "%q" $s}}{{end -}} },
+	})
+	if err != nil {
+		log.Fatalf("failed to set up auth: %v", err)
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()))
+{{end}}{{with .Options.RateLimit}}
+	// Shed requests beyond the method's rate limits, rather than queueing them.
+	limiter := ratelimit.New(ratelimit.Config{
+		RequestsPerSecond: {{.RequestsPerSecond}},
+		Burst:             {{.Burst}},
+		KeyHeader:         {{printf "%q" .KeyHeader}},
+		KeyClaim:          {{printf "%q" .KeyClaim}},
+		MaxInFlight:       {{.MaxInFlight}},
+	})
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor()))
+{{end}}{{if not .Options.SkipValidation}}
+	// Reject requests that violate their protoc-gen-validate rules.
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(validate.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(validate.StreamServerInterceptor()))
+{{end}}{{with .Options.Cache}}
+	// Serve repeated requests from the cache, since the method has no side
+	// effects.
+	responses := cache.New(cache.Config{
+		TTL:        {{.TtlSeconds}} * time.Second,
+		MaxEntries: {{.MaxEntries}},
+		KeyHeaders: []string{ {{- range $i, $h := .KeyHeaders}}{{if $i}}, {{end}}{{printf "%q" $h}}{{end -}} },
+	})
+	opts = append(opts, grpc.ChainUnaryInterceptor(responses.UnaryServerInterceptor()))
+{{end}}{{with .Options.Stream}}
+	// End long-lived streams with a resumable status before the revision's
+	// timeout, and keep idle ones alive through proxies.
+	streams := streaming.New(streaming.Config{
+		Heartbeat:   {{.HeartbeatSeconds}} * time.Second,
+		MaxDuration: {{.MaxDurationSeconds}} * time.Second,
+		NewResponse: func() interface{} { return &{{$.ResponseType}}{} },
+	})
+	opts = append(opts, grpc.ChainStreamInterceptor(streams.StreamServerInterceptor()))
+{{end}}
+	// Translate the errors returned by impl.Impl into statuses.
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(errors.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(errors.StreamServerInterceptor()))
+{{if .HasInit}}
+	// Set up the method's resources before we start accepting requests.
+	if err := impl.Init(context.Background()); err != nil {
+		log.Fatalf("failed to initialize {{.Method}}: %v", err)
+	}
+{{end}}
+{{if .Options.Metrics}}	// Prometheus scrapes the pod's IP, rather than going through the
+	// queue-proxy, so listen beyond localhost.
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", os.Getenv("PORT")))
+{{else}}	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%s", os.Getenv("PORT")))
+{{end}}	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+{{with .Options.Metrics}}
+	// Serve the metrics to Prometheus on the same port, since Knative only
+	// routes the one port to the container.
+	lis, err = metrics.Split(lis, "{{.Path}}")
+	if err != nil {
+		log.Fatalf("failed to serve metrics: %v", err)
+	}
+{{end}}
 	// The grpcServer is currently configured to serve h2c traffic by default.
 	// To configure credentials or encryption, see: https://grpc.io/docs/guides/auth.html#go
-	grpcServer := grpc.NewServer(grpc.StatsHandler(&ocgrpc.ServerHandler{}))
+	grpcServer := grpc.NewServer(opts...)
 
-	pb.Register{{.Service}}Server(grpcServer, &server{})
+	pb.Register{{.Service}}Server(grpcServer, &adapter.Server{})
 	healthpb.RegisterHealthServer(grpcServer, &health{})
 
+	// Stop accepting new requests and drain in-flight ones when asked to stop.
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+		<-sigCh
+{{if .Options.Stream}}		// Let clients resume their streams on another revision.
+		streams.Drain()
+{{end}}		grpcServer.GracefulStop()
+	}()
+
 	grpcServer.Serve(lis)
-}
+{{if .HasClose}}
+	// Release the method's resources once the server has drained.
+	if err := impl.Close(context.Background()); err != nil {
+		log.Printf("failed to close {{.Method}}: %v", err)
+	}
+{{end}}}
 
 // Based on github.com/grpc-ecosystem/grpc-health-probe
 func probe() {
@@ -110,14 +323,36 @@ func (h *health) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*
 func (h *health) Watch(*healthpb.HealthCheckRequest, healthpb.Health_WatchServer) error {
 	return status.Error(codes.Unimplemented, "korpc does not implement Watch")
 }
+`
+
+	adapterTemplate = `// Package adapter serves {{.Method}} by calling impl.Impl, translating
+// between the gRPC stream and the channels that impl.Impl is passed.
+package adapter
+
+import (
+	"context"
+	"io"
 
-// Don't complain about the import
+	pb "{{.ProtoImportPath}}"
+	impl "{{.ImplImportPath}}"
+{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
+
+// Server implements pb.{{.Service}}Server, serving {{.Method}} with impl.Impl.
+type Server struct {
+	pb.Unimplemented{{.Service}}Server
+}
+
+{{.Implementation}}
+
+// Don't complain about the imports
 var _ = io.EOF
+var _ context.Context
 `
 
 	streamInSkeleton = `
-func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
-	input := make(chan *pb.{{.RequestType}})
+{{.Doc}}func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
+	input := make(chan *{{.RequestType}})
 
 	errCh := make(chan error)
 
@@ -151,8 +386,8 @@ func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
 `
 
 	streamOutSkeleton = `
-func {{.Receiver}}{{.Name}}(input *pb.{{.RequestType}}, stream pb.{{.Service}}_{{.Method}}Server) error {
-	output := make(chan *pb.{{.ResponseType}})
+{{.Doc}}func {{.Receiver}}{{.Name}}(input *{{.RequestType}}, stream pb.{{.Service}}_{{.Method}}Server) error {
+	output := make(chan *{{.ResponseType}})
 	errCh := make(chan error)
 
 	go func() {
@@ -185,9 +420,9 @@ func {{.Receiver}}{{.Name}}(input *pb.{{.RequestType}}, stream pb.{{.Service}}_{
 `
 
 	streamInOutSkeleton = `
-func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
-	input := make(chan *pb.{{.RequestType}})
-	output := make(chan *pb.{{.ResponseType}})
+{{.Doc}}func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
+	input := make(chan *{{.RequestType}})
+	output := make(chan *{{.ResponseType}})
 
 	errCh := make(chan error)
 
@@ -230,12 +465,77 @@ func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
 
 	return <-errCh
 }
+`
+
+	// The stream that Impl is passed under stream_style: STREAM.  It is not
+	// registered, since Impl's signature depends on it.
+	streamTemplate = `// Package stream declares the stream with which impl.Impl serves {{.Method}}.
+package stream
+
+import (
+	"google.golang.org/grpc/metadata"
+{{if .UsesProto}}
+	pb "{{.ProtoImportPath}}"
+{{end}}{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
+
+// Server is the part of pb.{{.Service}}_{{.Method}}Server that impl.Impl
+// needs, so that tests can pass an implementation of their own.
+type Server interface {
+{{with .RequestType}}	// Recv returns the next request, or io.EOF once the client has closed
+	// its side of the stream.
+	Recv() (*{{.}}, error)
+{{end}}{{with .ResponseType}}	// Send sends a response, and fails once the stream is done.
+	Send(*{{.}}) error
+{{end}}
+	// SetHeader sets the header metadata, which is sent with the first
+	// response, or by SendHeader.
+	SetHeader(metadata.MD) error
+	// SendHeader sends the header metadata.
+	SendHeader(metadata.MD) error
+	// SetTrailer sets the trailer metadata, which is sent with the status.
+	SetTrailer(metadata.MD)
+}
+`
+
+	// The adapters of methods with stream_style: STREAM, which hand Impl the
+	// gRPC stream itself.
+	streamInOutStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
+	return impl.Impl(stream.Context(), stream)
+}
+`
+
+	streamInStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(stream pb.{{.Service}}_{{.Method}}Server) error {
+	resp, err := impl.Impl(stream.Context(), stream)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+`
+
+	streamOutStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(req *{{.RequestType}}, stream pb.{{.Service}}_{{.Method}}Server) error {
+	return impl.Impl(stream.Context(), req, stream)
+}
 `
 )
 
 var (
-	tmpl              = template.Must(template.New("entrypoint").Parse(entrypointTemplate))
 	streamInOutMethod = template.Must(template.New("stream-in-out").Parse(streamInOutSkeleton))
 	streamInMethod    = template.Must(template.New("stream-in").Parse(streamInSkeleton))
 	streamOutMethod   = template.Must(template.New("stream-out").Parse(streamOutSkeleton))
+
+	streamInOutStreamMethod = template.Must(template.New("stream-in-out-stream").Parse(streamInOutStreamSkeleton))
+	streamInStreamMethod    = template.Must(template.New("stream-in-stream").Parse(streamInStreamSkeleton))
+	streamOutStreamMethod   = template.Must(template.New("stream-out-stream").Parse(streamOutStreamSkeleton))
+
+	streamTmpl = template.Must(template.New("stream").Parse(streamTemplate))
 )
+
+func init() {
+	templates.Register(templateName, entrypointTemplate)
+	templates.Register(adapterTemplateName, adapterTemplate)
+}
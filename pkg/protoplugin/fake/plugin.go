@@ -0,0 +1,199 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/protoplugin"
+)
+
+type plugin struct {
+}
+
+var _ protoplugin.Interface = (*plugin)(nil)
+
+var (
+	docTmpl     = template.Must(template.New("doc").Parse(docTemplate))
+	serviceTmpl = template.Must(template.New("service").Parse(serviceTemplate))
+)
+
+func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	codegen := make(map[string]struct{})
+	for _, file := range request.FileToGenerate {
+		codegen[file] = struct{}{}
+	}
+
+	var resp plugin_go.CodeGeneratorResponse
+	docName := "doc.go"
+	docContent, err := execToString(docTmpl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if docContent, err = gofmt(docName, docContent); err != nil {
+		return nil, err
+	}
+	// The names that doc.go declares for the whole package.
+	pkg := declared{
+		"bufSize":      "the fake package",
+		"unconfigured": "the fake package",
+	}
+	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+		Name:    &docName,
+		Content: &docContent,
+	})
+
+	for _, fd := range request.ProtoFile {
+		if _, ok := codegen[fd.GetName()]; !ok {
+			continue
+		}
+
+		for _, sdp := range fd.Service {
+			r := gotypes.New(stuff, request, fd)
+			opt := &options{
+				Service:         sdp.GetName(),
+				FullService:     fmt.Sprintf("%s.%s", fd.GetPackage(), sdp.GetName()),
+				ProtoImportPath: gotypes.ImportPath(stuff, fd),
+			}
+			if err := pkg.add(sdp.GetName(), "service "+opt.FullService); err != nil {
+				return nil, err
+			}
+			// The names that the fake of the service declares (or embeds)
+			// besides those of its methods.
+			members := declared{
+				"Client": "the fake's Client method",
+				"mu":     "the fake's mutex",
+				"Unimplemented" + sdp.GetName() + "Server":          "the embedded Unimplemented" + sdp.GetName() + "Server",
+				"mustEmbedUnimplemented" + sdp.GetName() + "Server": "the embedded Unimplemented" + sdp.GetName() + "Server",
+			}
+			for _, mdp := range sdp.Method {
+				if err := declare(pkg, members, sdp, mdp); err != nil {
+					return nil, err
+				}
+				requestType, err := r.GoType(mdp.GetInputType())
+				if err != nil {
+					return nil, err
+				}
+				responseType, err := r.GoType(mdp.GetOutputType())
+				if err != nil {
+					return nil, err
+				}
+				opt.Methods = append(opt.Methods, method{
+					Method:          mdp.GetName(),
+					Requests:        lowerFirst(mdp.GetName()) + "Requests",
+					Wrapper:         lowerFirst(sdp.GetName()) + mdp.GetName() + "Server",
+					RequestType:     requestType,
+					ResponseType:    responseType,
+					ClientStreaming: mdp.GetClientStreaming(),
+					ServerStreaming: mdp.GetServerStreaming(),
+				})
+			}
+			opt.Imports = r.Imports()
+
+			name := strings.ToLower(sdp.GetName()) + ".go"
+			content, err := execToString(serviceTmpl, opt)
+			if err != nil {
+				return nil, err
+			}
+			if content, err = gofmt(name, content); err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+				Name:    &name,
+				Content: &content,
+			})
+		}
+	}
+	return &resp, nil
+}
+
+// declared maps the Go names in a scope of the fake to what declares them.
+type declared map[string]string
+
+// add declares name for by, failing if it is already declared.
+func (d declared) add(name, by string) error {
+	if prev, ok := d[name]; ok {
+		return fmt.Errorf("fake: %s needs the name %s, which %s already uses; rename one of them", by, name, prev)
+	}
+	d[name] = by
+	return nil
+}
+
+// declare adds the names that the fake declares for a method, e.g. an RPC
+// named Client would collide with the fake's Client method, and one named
+// FooFunc with the FooFunc field of an RPC named Foo.
+func declare(pkg, members declared, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) error {
+	by := fmt.Sprintf("%s.%s", sdp.GetName(), mdp.GetName())
+	name := mdp.GetName()
+	names := []string{
+		name,
+		name + "Func",
+		name + "Err",
+		name + "Requests",
+		"record" + name,
+		lowerFirst(name) + "Requests",
+	}
+	if mdp.GetServerStreaming() {
+		names = append(names, name+"Responses")
+	} else {
+		names = append(names, name+"Response")
+	}
+	for _, n := range names {
+		if err := members.add(n, by); err != nil {
+			return err
+		}
+	}
+	if mdp.GetClientStreaming() {
+		return pkg.add(lowerFirst(sdp.GetName())+name+"Server", by)
+	}
+	return nil
+}
+
+// gofmt formats the generated source, as if it were written by hand.
+func gofmt(name, src string) (string, error) {
+	b, err := format.Source([]byte(src))
+	if err != nil {
+		return "", fmt.Errorf("formatting %s: %v", name, err)
+	}
+	return string(b), nil
+}
+
+// lowerFirst lowercases the first letter of an exported name.
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// execute a template to produce a string.
+func execToString(t *template.Template, opt interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := t.Execute(buf, opt)
+	if err != nil {
+		return "", err
+	}
+	return string(buf.Bytes()), nil
+}
+
+func init() {
+	protoplugin.Register("fake", &plugin{})
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+func TestDeclare(t *testing.T) {
+	rpc := func(name string, clientStreaming, serverStreaming bool) *descriptor.MethodDescriptorProto {
+		return &descriptor.MethodDescriptorProto{
+			Name:            proto.String(name),
+			ClientStreaming: proto.Bool(clientStreaming),
+			ServerStreaming: proto.Bool(serverStreaming),
+		}
+	}
+	service := func(name string, methods ...*descriptor.MethodDescriptorProto) *descriptor.ServiceDescriptorProto {
+		return &descriptor.ServiceDescriptorProto{Name: proto.String(name), Method: methods}
+	}
+
+	tests := []struct {
+		name     string
+		services []*descriptor.ServiceDescriptorProto
+		wantErr  bool
+	}{{
+		name: "distinct",
+		services: []*descriptor.ServiceDescriptorProto{
+			service("Foo", rpc("Get", false, false), rpc("List", false, true), rpc("Upload", true, false)),
+			service("Bar", rpc("Get", false, false), rpc("Upload", true, true)),
+		},
+	}, {
+		name:     "Client",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("Client", false, false))},
+		wantErr:  true,
+	}, {
+		name:     "Func",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("Get", false, false), rpc("GetFunc", false, false))},
+		wantErr:  true,
+	}, {
+		name:     "Response",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("GetResponse", false, false), rpc("Get", false, false))},
+		wantErr:  true,
+	}, {
+		name:     "Responses",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("List", false, true), rpc("ListResponses", false, false))},
+		wantErr:  true,
+	}, {
+		name:     "Err",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("Get", false, false), rpc("GetErr", false, false))},
+		wantErr:  true,
+	}, {
+		name:     "Requests",
+		services: []*descriptor.ServiceDescriptorProto{service("Foo", rpc("Get", false, false), rpc("GetRequests", false, false))},
+		wantErr:  true,
+	}, {
+		name: "wrappers",
+		services: []*descriptor.ServiceDescriptorProto{
+			service("Foo", rpc("BarUpload", true, false)),
+			service("FooBar", rpc("Upload", true, false)),
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pkg := declared{}
+			var err error
+			for _, sdp := range test.services {
+				members := declared{"Client": "the fake's Client method"}
+				for _, mdp := range sdp.Method {
+					if err = declare(pkg, members, sdp, mdp); err != nil {
+						break
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+			if (err != nil) != test.wantErr {
+				t.Errorf("declare() = %v, wanted error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"github.com/mattmoor/korpc/pkg/gotypes"
+)
+
+type options struct {
+	Service         string
+	FullService     string
+	ProtoImportPath string
+	Imports         []gotypes.Import
+	Methods         []method
+}
+
+type method struct {
+	Method          string
+	Requests        string
+	Wrapper         string
+	RequestType     string
+	ResponseType    string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+const (
+	docTemplate = `// Package fake contains in-memory fakes of the services in this API, for
+// testing code that calls them.  Each fake serves a method with its Func
+// when set, or else with its canned response and error, and records the
+// requests it receives.
+package fake
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The size of the in-memory connection's buffer.
+const bufSize = 1 << 20
+
+// unconfigured is returned by methods that have been given nothing to
+// respond with.
+func unconfigured(method string) error {
+	return status.Error(codes.Unimplemented,
+		fmt.Sprintf("fake: %s has no Func, response or error", method))
+}
+`
+
+	serviceTemplate = `package fake
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mattmoor/korpc/pkg/runtime/errors"
+
+	pb "{{.ProtoImportPath}}"
+{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
+
+// {{.Service}} is an in-memory {{.FullService}}.  Set its fields before
+// making calls; a method with no Func, response or error fails with
+// UNIMPLEMENTED.
+type {{.Service}} struct {
+	pb.Unimplemented{{.Service}}Server
+{{range .Methods}}
+{{if .ClientStreaming}}{{if .ServerStreaming}}	// {{.Method}}Func serves {{.Method}} when set.  Otherwise {{.Method}}Responses
+	// are sent, the requests are read until the client closes its side, and
+	// {{.Method}}Err is returned.
+	{{.Method}}Func      func(pb.{{$.Service}}_{{.Method}}Server) error
+	{{.Method}}Responses []*{{.ResponseType}}
+{{else}}	// {{.Method}}Func serves {{.Method}} when set.  Otherwise the requests are read
+	// until the client closes its side, and {{.Method}}Response or {{.Method}}Err is
+	// returned.
+	{{.Method}}Func     func(pb.{{$.Service}}_{{.Method}}Server) error
+	{{.Method}}Response *{{.ResponseType}}
+{{end}}{{else if .ServerStreaming}}	// {{.Method}}Func serves {{.Method}} when set.  Otherwise {{.Method}}Responses
+	// are sent, and {{.Method}}Err is returned.
+	{{.Method}}Func      func(*{{.RequestType}}, pb.{{$.Service}}_{{.Method}}Server) error
+	{{.Method}}Responses []*{{.ResponseType}}
+{{else}}	// {{.Method}}Func serves {{.Method}} when set.  Otherwise {{.Method}}Response or
+	// {{.Method}}Err is returned.
+	{{.Method}}Func     func(context.Context, *{{.RequestType}}) (*{{.ResponseType}}, error)
+	{{.Method}}Response *{{.ResponseType}}
+{{end}}	{{.Method}}Err error
+{{end}}
+	mu sync.Mutex
+{{range .Methods}}	{{.Requests}} []*{{.RequestType}}
+{{end}}}
+
+var _ pb.{{.Service}}Server = (*{{.Service}})(nil)
+
+// Client serves f over an in-memory connection, and returns a client for it
+// along with a function that closes the connection and stops the server.
+func (f *{{.Service}}) Client(ctx context.Context, opts ...grpc.DialOption) (pb.{{.Service}}Client, func(), error) {
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(errors.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(errors.StreamServerInterceptor()))
+	pb.Register{{.Service}}Server(s, f)
+	go s.Serve(lis)
+
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+	}, opts...)
+	conn, err := grpc.DialContext(ctx, "bufnet", opts...)
+	if err != nil {
+		s.Stop()
+		return nil, nil, err
+	}
+	return pb.New{{.Service}}Client(conn), func() {
+		conn.Close()
+		s.Stop()
+	}, nil
+}
+{{range .Methods}}
+// {{.Method}}Requests returns the {{.Method}} requests received so far.
+func (f *{{$.Service}}) {{.Method}}Requests() []*{{.RequestType}} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*{{.RequestType}}(nil), f.{{.Requests}}...)
+}
+
+func (f *{{$.Service}}) record{{.Method}}(req *{{.RequestType}}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.{{.Requests}} = append(f.{{.Requests}}, req)
+}
+{{if .ClientStreaming}}
+// {{.Wrapper}} records the requests as they are received.
+type {{.Wrapper}} struct {
+	pb.{{$.Service}}_{{.Method}}Server
+	f *{{$.Service}}
+}
+
+func (s *{{.Wrapper}}) Recv() (*{{.RequestType}}, error) {
+	req, err := s.{{$.Service}}_{{.Method}}Server.Recv()
+	if err == nil {
+		s.f.record{{.Method}}(req)
+	}
+	return req, err
+}
+
+// {{.Method}} implements pb.{{$.Service}}Server.
+func (f *{{$.Service}}) {{.Method}}(stream pb.{{$.Service}}_{{.Method}}Server) error {
+	stream = &{{.Wrapper}}{stream, f}
+	if f.{{.Method}}Func != nil {
+		return f.{{.Method}}Func(stream)
+	}
+{{if .ServerStreaming}}	if f.{{.Method}}Responses == nil && f.{{.Method}}Err == nil {
+		return unconfigured("{{.Method}}")
+	}
+	for _, resp := range f.{{.Method}}Responses {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			return f.{{.Method}}Err
+		} else if err != nil {
+			return err
+		}
+	}
+{{else}}	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if f.{{.Method}}Err != nil {
+		return f.{{.Method}}Err
+	}
+	if f.{{.Method}}Response == nil {
+		return unconfigured("{{.Method}}")
+	}
+	return stream.SendAndClose(f.{{.Method}}Response)
+{{end}}}
+{{else if .ServerStreaming}}
+// {{.Method}} implements pb.{{$.Service}}Server.
+func (f *{{$.Service}}) {{.Method}}(req *{{.RequestType}}, stream pb.{{$.Service}}_{{.Method}}Server) error {
+	f.record{{.Method}}(req)
+	if f.{{.Method}}Func != nil {
+		return f.{{.Method}}Func(req, stream)
+	}
+	if f.{{.Method}}Responses == nil && f.{{.Method}}Err == nil {
+		return unconfigured("{{.Method}}")
+	}
+	for _, resp := range f.{{.Method}}Responses {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return f.{{.Method}}Err
+}
+{{else}}
+// {{.Method}} implements pb.{{$.Service}}Server.
+func (f *{{$.Service}}) {{.Method}}(ctx context.Context, req *{{.RequestType}}) (*{{.ResponseType}}, error) {
+	f.record{{.Method}}(req)
+	if f.{{.Method}}Func != nil {
+		return f.{{.Method}}Func(ctx, req)
+	}
+	if f.{{.Method}}Err != nil {
+		return nil, f.{{.Method}}Err
+	}
+	if f.{{.Method}}Response == nil {
+		return nil, unconfigured("{{.Method}}")
+	}
+	return f.{{.Method}}Response, nil
+}
+{{end}}{{end}}
+// Don't complain about the import
+var _ = io.EOF
+`
+)
@@ -19,11 +19,13 @@ import (
 	"fmt"
 	"text/template"
 
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/golang/protobuf/protoc-gen-go/plugin"
 
 	"github.com/mattmoor/korpc/pkg/naming"
 	"github.com/mattmoor/korpc/pkg/parameter"
 	"github.com/mattmoor/korpc/pkg/protoplugin"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
 type plugin struct {
@@ -31,8 +33,6 @@ type plugin struct {
 
 var _ protoplugin.Interface = (*plugin)(nil)
 
-var tmpl = template.Must(template.New("gateway").Parse(gatewayTemplate))
-
 func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
 	codegen := make(map[string]struct{})
 	for _, file := range request.FileToGenerate {
@@ -44,6 +44,10 @@ func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequ
 		Name:      "grpc-gateway",
 		Namespace: stuff.Namespace,
 		Domain:    stuff.Domain,
+		Domains:   stuff.Domains,
+	}
+	if len(opt.Domains) == 0 {
+		opt.Domains = []string{stuff.Domain}
 	}
 	for _, fd := range request.ProtoFile {
 		if _, ok := codegen[fd.GetName()]; !ok {
@@ -55,12 +59,17 @@ func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequ
 				opt.RoutingRules = append(opt.RoutingRules, routingRule{
 					Path:        fmt.Sprintf("/%s.%s/%s", fd.GetPackage(), sdp.GetName(), mdp.GetName()),
 					ServiceName: naming.Service(sdp, mdp),
+					Retry:       retriable(mdp),
 				})
 			}
 		}
 	}
 
 	// Based on the accumulated rules generate the dispatch yaml.
+	tmpl, err := templates.Parse(stuff.Templates, templateName)
+	if err != nil {
+		return nil, err
+	}
 	mainName := "gateway.yaml"
 	mainContent, err := execToString(tmpl, opt)
 	if err != nil {
@@ -74,6 +83,17 @@ func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequ
 	return &resp, nil
 }
 
+// retriable reports whether the method is safe to retry, because it has no
+// side effects or is idempotent.
+func retriable(mdp *descriptor.MethodDescriptorProto) bool {
+	switch mdp.GetOptions().GetIdempotencyLevel() {
+	case descriptor.MethodOptions_NO_SIDE_EFFECTS, descriptor.MethodOptions_IDEMPOTENT:
+		return true
+	default:
+		return false
+	}
+}
+
 // execute a template to produce a string.
 func execToString(t *template.Template, opt interface{}) (string, error) {
 	buf := &bytes.Buffer{}
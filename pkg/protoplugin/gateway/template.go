@@ -14,16 +14,36 @@
 
 package gateway
 
+import (
+	"github.com/mattmoor/korpc/pkg/templates"
+)
+
+// templateName is the file from which projects may override gatewayTemplate.
+const templateName = "gateway.yaml.tmpl"
+
+// options is the data with which the API's VirtualService is generated.
 type options struct {
-	Name         string
-	Namespace    string
-	Domain       string
+	// Name is the name of the VirtualService.
+	Name string
+	// Namespace is the namespace into which it is deployed.
+	Namespace string
+	// Domain is the domain on which the API is served.
+	Domain string
+	// Domains are all of the domains on which the API is served, the first
+	// of which is Domain.
+	Domains []string
+	// RoutingRules route each RPC to its method's Knative Service.
 	RoutingRules []routingRule
 }
 
 type routingRule struct {
-	Path        string
+	// Path is the HTTP/2 path of the RPC, i.e. /<package>.<service>/<method>.
+	Path string
+	// ServiceName is the name of the method's Knative Service.
 	ServiceName string
+	// Retry is set for methods that are safe to retry, per their
+	// idempotency_level.
+	Retry bool
 }
 
 const (
@@ -37,7 +57,9 @@ spec:
   - knative-ingress-gateway.knative-serving.svc.cluster.local
   - mesh
   hosts:
-  - {{$.Domain}}
+{{- range $.Domains}}
+  - {{.}}
+{{- end}}
   http:
 {{range $val := .RoutingRules}}
   - match:
@@ -51,6 +73,15 @@ spec:
           port:
             number: 80
         weight: 100
+{{- if $val.Retry}}
+    retries:
+      attempts: 3
+      retryOn: connect-failure,refused-stream,reset,unavailable
+{{- end}}
 {{end}}
 `
 )
+
+func init() {
+	templates.Register(templateName, gatewayTemplate)
+}
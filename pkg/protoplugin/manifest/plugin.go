@@ -0,0 +1,61 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/manifest"
+	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/protoplugin"
+)
+
+type plugin struct {
+}
+
+var _ protoplugin.Interface = (*plugin)(nil)
+
+func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	codegen := make(map[string]struct{})
+	for _, file := range request.FileToGenerate {
+		codegen[file] = struct{}{}
+	}
+	var files []*descriptor.FileDescriptorProto
+	for _, fd := range request.ProtoFile {
+		if _, ok := codegen[fd.GetName()]; ok {
+			files = append(files, fd)
+		}
+	}
+
+	b, err := json.MarshalIndent(manifest.New(stuff, files), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp plugin_go.CodeGeneratorResponse
+	name, content := manifest.Name, string(b)+"\n"
+	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+		Name:    &name,
+		Content: &content,
+	})
+	return &resp, nil
+}
+
+func init() {
+	protoplugin.Register("manifest", &plugin{})
+}
@@ -19,8 +19,10 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/plugin"
 
 	"github.com/mattmoor/korpc/pkg/parameter"
@@ -51,6 +53,17 @@ func Get(name string) Interface {
 	return plugins[name]
 }
 
+// Nest adds the files of resp, whose names are relative to dir, to into,
+// whose names are relative to the parent of dir.  This lets plugins generate
+// the files of each method in-process, rather than via //go:generate.
+func Nest(into *plugin_go.CodeGeneratorResponse, dir string, resp *plugin_go.CodeGeneratorResponse) {
+	for _, file := range resp.File {
+		name := filepath.Join(dir, file.GetName())
+		file.Name = &name
+		into.File = append(into.File, file)
+	}
+}
+
 func dispatch(request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
 	stuff, err := parameter.From(request.GetParameter())
 	if err != nil {
@@ -70,7 +83,7 @@ func Run() error {
 	}
 
 	var req plugin_go.CodeGeneratorRequest
-	if err := req.Unmarshal(bytes); err != nil {
+	if err := proto.Unmarshal(bytes, &req); err != nil {
 		return err
 	}
 
@@ -84,7 +97,7 @@ func Run() error {
 		}
 	}
 
-	result, err := resp.Marshal(nil, true)
+	result, err := proto.Marshal(resp)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,322 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/protoplugin"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/config"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/entrypoint"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/methods"
+	_ "github.com/mattmoor/korpc/pkg/protoplugin/scaffold"
+)
+
+// asPlugin makes the test binary act as korpc when protoc would run it, so
+// that the benchmarks pay for the processes that protoc starts.
+const asPlugin = "KORPC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(asPlugin) != "" {
+		if err := protoplugin.Run(); err != nil {
+			log.Fatalf("Error running the plugin: %v", err)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// request returns what protoc passes korpc for a service with n methods,
+// which take turns being unary and each kind of streaming.  The last has
+// stream_style: STREAM.
+func request(n int) *plugin_go.CodeGeneratorRequest {
+	message := func(name string) *descriptor.DescriptorProto {
+		return &descriptor.DescriptorProto{Name: proto.String(name)}
+	}
+	sdp := &descriptor.ServiceDescriptorProto{Name: proto.String("SampleService")}
+	for i := 0; i < n; i++ {
+		sdp.Method = append(sdp.Method, &descriptor.MethodDescriptorProto{
+			Name:            proto.String(fmt.Sprintf("Method%d", i)),
+			InputType:       proto.String(".sample.Request"),
+			OutputType:      proto.String(".sample.Response"),
+			ClientStreaming: proto.Bool(i%2 == 1),
+			ServerStreaming: proto.Bool(i%4 >= 2),
+		})
+	}
+	opts := &descriptor.MethodOptions{}
+	if err := proto.SetExtension(opts, korpc.E_Options, &korpc.Options{StreamStyle: korpc.Options_STREAM}); err != nil {
+		log.Fatalf("SetExtension() = %v", err)
+	}
+	sdp.Method[len(sdp.Method)-1].Options = opts
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:        proto.String("service.proto"),
+		Package:     proto.String("sample"),
+		MessageType: []*descriptor.DescriptorProto{message("Request"), message("Response")},
+		Service:     []*descriptor.ServiceDescriptorProto{sdp},
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("example.com/sample/gen/proto;sample"),
+		},
+		Syntax: proto.String("proto3"),
+	}
+	return &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptor.FileDescriptorProto{fd},
+	}
+}
+
+// stuff returns the parameters that `korpc generate` passes the named plugin.
+func stuff(name, nested string, modules bool) parameter.Stuff {
+	return parameter.Stuff{
+		Name:            name,
+		Base:            "example.com/sample",
+		GenDir:          "gen",
+		MethodsDir:      "pkg/methods",
+		Domain:          "sample.io",
+		Domains:         []string{"sample.io"},
+		Namespace:       "default",
+		Modules:         modules,
+		NestedDirectory: nested,
+	}
+}
+
+// invocation is a run of a plugin, from dir relative to the root of the
+// repository, whose files are written to dir/out.
+type invocation struct {
+	stuff parameter.Stuff
+	dir   string
+	out   string
+}
+
+// singlePass returns the invocations that `korpc generate` makes of the
+// plugins that generate each method's files, all from the root.
+func singlePass(modules bool) []invocation {
+	return []invocation{
+		{stuff: stuff("entrypoint", "gen/entrypoint", modules), out: "gen/entrypoint"},
+		{stuff: stuff("config", "gen/config", modules), out: "gen/config"},
+		{stuff: stuff("methods", "pkg/methods", modules), out: "pkg/methods"},
+	}
+}
+
+// fanOut returns the invocations that the //go:generate lines of the
+// korpc.go files made, one per plugin and method, each from the directory
+// holding its korpc.go.
+func fanOut(request *plugin_go.CodeGeneratorRequest, modules bool) []invocation {
+	var invs []invocation
+	for _, sdp := range request.ProtoFile[0].Service {
+		for _, mdp := range sdp.Method {
+			dir := filepath.Join(strings.ToLower(sdp.GetName()), strings.ToLower(mdp.GetName()))
+			method := func(name, nested string) parameter.Stuff {
+				s := stuff(name, nested, modules)
+				s.Service, s.Method = sdp.GetName(), mdp.GetName()
+				return s
+			}
+			invs = append(invs, invocation{
+				stuff: method("entrypoint", filepath.Join("gen/entrypoint", dir)),
+				dir:   "gen/entrypoint",
+				out:   filepath.Join("gen/entrypoint", dir),
+			}, invocation{
+				stuff: method("config", "gen/config"),
+				dir:   "gen/config",
+				out:   "gen/config",
+			}, invocation{
+				stuff: method("scaffold", filepath.Join("pkg/methods", dir)),
+				dir:   filepath.Join("pkg/methods", dir),
+				out:   filepath.Join("pkg/methods", dir),
+			})
+		}
+	}
+	return invs
+}
+
+// seeded are the files of a repository in which some methods are already
+// implemented: Method0 along with an Init hook, and Method1 with the
+// signature of a unary method, which the scaffold rewrites.
+var seeded = map[string]string{
+	"go.mod": "module example.com/sample\n\ngo 1.21\n",
+	"pkg/methods/sampleservice/method0/main.go": `package method0
+
+import (
+	"context"
+
+	pb "example.com/sample/gen/proto"
+)
+
+func Init(ctx context.Context) error {
+	return nil
+}
+
+func Impl(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return &pb.Response{}, nil
+}
+`,
+	"pkg/methods/sampleservice/method1/main.go": `package method1
+
+import (
+	"context"
+
+	pb "example.com/sample/gen/proto"
+)
+
+func Impl(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return &pb.Response{}, nil
+}
+`,
+}
+
+// generate runs the invocations in-process from the root of a repository
+// holding the seeds, and returns the files they generate by their paths from
+// the root.
+func generate(t *testing.T, root string, request *plugin_go.CodeGeneratorRequest, invs []invocation, seeds map[string]string) map[string]string {
+	t.Helper()
+	for name, content := range seeds {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatalf("MkdirAll() = %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatalf("WriteFile() = %v", err)
+		}
+	}
+
+	files := make(map[string]string)
+	for _, inv := range invs {
+		dir := filepath.Join(root, inv.dir)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatalf("MkdirAll() = %v", err)
+		}
+		t.Chdir(dir)
+		stuff := inv.stuff
+		resp, err := protoplugin.Get(stuff.Name).Do(&stuff, request)
+		if err != nil {
+			t.Fatalf("%s: Do() = %v", stuff.Name, err)
+		}
+		for _, f := range resp.File {
+			name := filepath.Join(inv.out, f.GetName())
+			if _, ok := files[name]; ok {
+				t.Errorf("%s is generated twice", name)
+			}
+			files[name] = f.GetContent()
+		}
+	}
+	return files
+}
+
+// The single pass must generate exactly what the fan-out of protoc
+// invocations did.
+func TestSinglePass(t *testing.T) {
+	request := request(8)
+	for _, modules := range []bool{false, true} {
+		t.Run(fmt.Sprint("modules=", modules), func(t *testing.T) {
+			got := generate(t, t.TempDir(), request, singlePass(modules), seeded)
+			want := generate(t, t.TempDir(), request, fanOut(request, modules), seeded)
+			if len(want) == 0 {
+				t.Fatal("The fan-out generated nothing")
+			}
+
+			var names []string
+			for name := range want {
+				names = append(names, name)
+			}
+			for name := range got {
+				if _, ok := want[name]; !ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				g, gok := got[name]
+				w, wok := want[name]
+				switch {
+				case !gok:
+					t.Errorf("%s is not generated", name)
+				case !wok:
+					t.Errorf("%s is generated, but wasn't before", name)
+				case g != w:
+					t.Errorf("%s = %s, wanted %s", name, g, w)
+				}
+			}
+		})
+	}
+}
+
+// run runs the test binary as the plugin, as protoc does, from dir.
+func run(b *testing.B, dir string, request *plugin_go.CodeGeneratorRequest, stuff parameter.Stuff) {
+	b.Helper()
+	request.Parameter = proto.String(stuff.MustEncode())
+	in, err := proto.Marshal(request)
+	if err != nil {
+		b.Fatalf("Marshal() = %v", err)
+	}
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), asPlugin+"=1")
+	cmd.Stdin = bytes.NewReader(in)
+	out, err := cmd.Output()
+	if err != nil {
+		b.Fatalf("%s: %v", stuff.Name, err)
+	}
+	var resp plugin_go.CodeGeneratorResponse
+	if err := proto.Unmarshal(out, &resp); err != nil {
+		b.Fatalf("Unmarshal() = %v", err)
+	}
+	if resp.Error != nil {
+		b.Fatalf("%s: %s", stuff.Name, resp.GetError())
+	}
+}
+
+// BenchmarkGenerate compares generating the methods' files of a 50-method
+// API in a single pass with the old fan-out, starting a plugin process for
+// each invocation.  It leaves out the protoc that the fan-out also started
+// for each, which parsed the protos again.
+func BenchmarkGenerate(b *testing.B) {
+	request := request(50)
+	for _, bm := range []struct {
+		name string
+		invs []invocation
+	}{
+		{"single-pass", singlePass(false)},
+		{"fan-out", fanOut(request, false)},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			root := b.TempDir()
+			for _, inv := range bm.invs {
+				if err := os.MkdirAll(filepath.Join(root, inv.dir), 0777); err != nil {
+					b.Fatalf("MkdirAll() = %v", err)
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, inv := range bm.invs {
+					run(b, filepath.Join(root, inv.dir), request, inv.stuff)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package scaffold
 import (
 	"bytes"
 	"fmt"
+	"log"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -24,8 +25,14 @@ import (
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/golang/protobuf/protoc-gen-go/plugin"
 
+	korpc "github.com/mattmoor/korpc/include"
+	"github.com/mattmoor/korpc/pkg/comments"
+	"github.com/mattmoor/korpc/pkg/defaults"
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/modules"
 	"github.com/mattmoor/korpc/pkg/parameter"
 	"github.com/mattmoor/korpc/pkg/protoplugin"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
 type plugin struct {
@@ -34,45 +41,156 @@ type plugin struct {
 var _ protoplugin.Interface = (*plugin)(nil)
 
 func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	// We are run from the method's directory.
+	return Generate(stuff, request, ".")
+}
+
+// Generate scaffolds the method that stuff names in dir, its directory
+// relative to the one from which we are run, and returns the files to write
+// there.  It reads what dir already holds, so that we can avoid clobbering
+// methods that have already been implemented.
+func Generate(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest, dir string) (*plugin_go.CodeGeneratorResponse, error) {
 	fd, sdp, mdp := getDescriptors(stuff, request)
 	if fd == nil || sdp == nil || mdp == nil {
 		return nil, fmt.Errorf("Unable to find %s.%s", stuff.Service, stuff.Method)
 	}
+	if path := defaults.Options(mdp).ImplImportPath; path != "" {
+		return nil, fmt.Errorf("%s.%s is implemented by %s, so there is nothing to scaffold", sdp.GetName(), mdp.GetName(), path)
+	}
+
+	r := gotypes.New(stuff, request, fd)
+	fn, err := unimpl(sdp, mdp, r, comments.Doc(fd, sdp, mdp))
+	if err != nil {
+		return nil, err
+	}
+	protoImportPath := gotypes.ImportPath(stuff, fd)
+	method := fmt.Sprintf("%s.%s", sdp.GetName(), mdp.GetName())
+	imports := r.Imports()
+	if path := streamImportPath(stuff, mdp); path != "" {
+		imports = append(imports, gotypes.Import{Alias: "stream", Path: path})
+	}
 
-	body, err := unimpl(sdp, mdp)
+	e, err := findImpl(dir)
 	if err != nil {
 		return nil, err
 	}
+	var resp plugin_go.CodeGeneratorResponse
+
+	// Tests are only scaffolded once, after which they belong to the user.
+	testName := "main_test.go"
+	if !exists(filepath.Join(dir, testName)) {
+		testContent, err := tests(stuff, fd, sdp, mdp, r, e == nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &testName,
+			Content: &testContent,
+		})
+		log.Printf("Created tests for %s", method)
+	}
 
-	opt := &options{
-		Package: strings.ToLower(mdp.GetName()),
-		ProtoImportPath: filepath.Join(stuff.Base, stuff.GenDir, "proto",
-			// protoc-gen-go includes directory names
-			filepath.Dir(fd.GetName())),
-		Body: body,
+	// Like the tests, the go.mod belongs to the user once created.
+	modName := "go.mod"
+	if stuff.Modules && !exists(filepath.Join(dir, modName)) {
+		modContent, err := goMod(stuff, dir)
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &modName,
+			Content: &modContent,
+		})
+		log.Printf("Created module for %s", method)
 	}
 
-	mainName := "main.go"
-	mainContent, err := execToString(tmpl, opt)
+	if e == nil {
+		mainName := "main.go"
+		if exists(filepath.Join(dir, mainName)) {
+			return nil, fmt.Errorf("%s: %s exists, but does not define Impl", method, mainName)
+		}
+		tmpl, err := templates.Parse(stuff.Templates, templateName)
+		if err != nil {
+			return nil, err
+		}
+		mainContent, err := execToString(tmpl, &options{
+			Package:         strings.ToLower(mdp.GetName()),
+			ProtoImportPath: protoImportPath,
+			Imports:         imports,
+			Body:            fn,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The template imports the proto package and those of the types,
+		// which go unused when the signature doesn't name them (e.g. with
+		// stream_style: STREAM, or both types are google.protobuf.Empty).
+		paths := []string{protoImportPath}
+		for _, imp := range imports {
+			paths = append(paths, imp.Path)
+		}
+		if mainContent, err = dropUnused(mainName, mainContent, paths...); err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &mainName,
+			Content: &mainContent,
+		})
+		log.Printf("Created %s", method)
+		return &resp, nil
+	}
+
+	names := map[string]string{
+		"context":                       "",
+		"google.golang.org/grpc/codes":  "",
+		"google.golang.org/grpc/status": "",
+		protoImportPath:                 "pb",
+	}
+	for _, imp := range imports {
+		names[imp.Path] = imp.Alias
+	}
+	want, err := parseFunc(fn)
 	if err != nil {
 		return nil, err
 	}
+	// The existing Impl may import the packages of its types under other
+	// names, so compare the packages themselves.
+	if signature(want.Type, scope(names)) == signature(e.decl.Type, e.scope()) {
+		log.Printf("Left %s untouched", method)
+		return &resp, nil
+	}
 
-	var resp plugin_go.CodeGeneratorResponse
+	// Keep the existing doc comment, which belongs to the user by now.
+	if fn, err = unimpl(sdp, mdp, r, ""); err != nil {
+		return nil, err
+	}
+	content, err := e.rewrite(fn, names)
+	if err != nil {
+		return nil, err
+	}
+	name, contentStr := filepath.Base(e.filename), string(content)
 	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
-		Name:    &mainName,
-		Content: &mainContent,
+		Name:    &name,
+		Content: &contentStr,
 	})
+	log.Printf("Updated the signature of %s in %s", method, name)
 	return &resp, nil
 }
 
-func unimpl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto) (string, error) {
+func unimpl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto, r *gotypes.Resolver, doc string) (string, error) {
 	t, et := UnaryMethod, UnaryError
+	stream := defaults.Options(mdp).GetStreamStyle() == korpc.Options_STREAM
 	switch {
+	case mdp.GetServerStreaming() && mdp.GetClientStreaming() && stream:
+		t, et = streamInOutStreamMethod, StreamOutError
 	case mdp.GetServerStreaming() && mdp.GetClientStreaming():
 		t, et = StreamInOutMethod, StreamInOutError
+	case mdp.GetClientStreaming() && stream:
+		t, et = streamInStreamMethod, UnaryError
 	case mdp.GetClientStreaming():
 		t, et = StreamInMethod, StreamInError
+	case mdp.GetServerStreaming() && stream:
+		t, et = streamOutStreamMethod, StreamOutError
 	case mdp.GetServerStreaming():
 		t, et = StreamOutMethod, StreamOutError
 	}
@@ -83,24 +201,90 @@ func unimpl(sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescri
 		return "", err
 	}
 
+	requestType, err := r.GoType(mdp.GetInputType())
+	if err != nil {
+		return "", err
+	}
+	responseType, err := r.GoType(mdp.GetOutputType())
+	if err != nil {
+		return "", err
+	}
+
 	opt := map[string]string{
 		"Service":      sdp.GetName(),
 		"Method":       mdp.GetName(),
-		"RequestType":  extract(mdp.GetInputType()),
-		"ResponseType": extract(mdp.GetOutputType()),
+		"RequestType":  requestType,
+		"ResponseType": responseType,
 		// The package name is the RPC method, so in this context we simply name
 		// the method "Impl" to avoid a stutter.  The method is also receiverless.
 		"Name":     "Impl",
 		"Receiver": "",
 		"Body":     body,
+		"Doc":      doc,
 	}
 	return execToString(t, opt)
 }
 
-// proto types come through as `.package.TypeName` so extract the last portion.
-func extract(t string) string {
-	parts := strings.Split(t, ".")
-	return parts[len(parts)-1]
+// tests returns the scaffolded tests for the method, which call Impl both
+// directly and through the generated adapter.  Only when Impl is scaffolded
+// along with them (unimplemented) do we know what it returns, and can seed a
+// case.
+func tests(stuff *parameter.Stuff, fd *descriptor.FileDescriptorProto, sdp *descriptor.ServiceDescriptorProto, mdp *descriptor.MethodDescriptorProto, r *gotypes.Resolver, unimplemented bool) (string, error) {
+	requestType, err := r.GoType(mdp.GetInputType())
+	if err != nil {
+		return "", err
+	}
+	responseType, err := r.GoType(mdp.GetOutputType())
+	if err != nil {
+		return "", err
+	}
+
+	service, method := strings.ToLower(sdp.GetName()), strings.ToLower(mdp.GetName())
+	opt := &testOptions{
+		Package:           method,
+		Service:           sdp.GetName(),
+		Method:            mdp.GetName(),
+		ProtoImportPath:   gotypes.ImportPath(stuff, fd),
+		ImplImportPath:    filepath.Join(stuff.Base, stuff.MethodsDir, service, method),
+		AdapterImportPath: filepath.Join(stuff.Base, stuff.GenDir, "entrypoint", service, method, "adapter"),
+		StreamImportPath:  streamImportPath(stuff, mdp),
+		RequestType:       requestType,
+		ResponseType:      responseType,
+		ClientStreaming:   mdp.GetClientStreaming(),
+		ServerStreaming:   mdp.GetServerStreaming(),
+		Streaming:         mdp.GetClientStreaming() || mdp.GetServerStreaming(),
+		Unimplemented:     unimplemented,
+	}
+	opt.Channels = opt.Streaming && defaults.Options(mdp).GetStreamStyle() == korpc.Options_CHANNELS
+
+	t := unaryTest
+	switch {
+	case mdp.GetServerStreaming() && mdp.GetClientStreaming():
+		t = streamInOutTest
+	case mdp.GetClientStreaming():
+		t = streamInTest
+	case mdp.GetServerStreaming():
+		t = streamOutTest
+	}
+	if opt.Body, err = execToString(t, opt); err != nil {
+		return "", err
+	}
+	opt.Imports = r.Imports()
+	return execToString(testTmpl, opt)
+}
+
+// streamImportPath returns the import path of the stream package that the
+// entrypoint generates for a method with stream_style: STREAM, or "" when
+// the method has another style.
+func streamImportPath(stuff *parameter.Stuff, mdp *descriptor.MethodDescriptorProto) string {
+	if !mdp.GetClientStreaming() && !mdp.GetServerStreaming() {
+		return ""
+	}
+	if defaults.Options(mdp).GetStreamStyle() != korpc.Options_STREAM {
+		return ""
+	}
+	return filepath.Join(stuff.Base, stuff.GenDir, "entrypoint",
+		strings.ToLower(stuff.Service), strings.ToLower(stuff.Method), "stream")
 }
 
 func getDescriptors(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*descriptor.FileDescriptorProto, *descriptor.ServiceDescriptorProto, *descriptor.MethodDescriptorProto) {
@@ -123,6 +307,27 @@ func getDescriptors(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequ
 	return nil, nil, nil
 }
 
+// goMod renders the go.mod of the method's module in dir, which requires the
+// generated proto package, and the entrypoint whose adapter the tests serve,
+// from the directories of their modules.
+func goMod(stuff *parameter.Stuff, dir string) (string, error) {
+	goVersion, err := modules.GoVersion(filepath.Join(dir, stuff.NestingEscape()))
+	if err != nil {
+		return "", err
+	}
+	var replaces []modules.Replace
+	for _, dir := range []string{
+		modules.ProtoDir(stuff),
+		modules.EntrypointDir(stuff, stuff.Service, stuff.Method),
+	} {
+		replaces = append(replaces, modules.Replace{
+			Path: modules.Path(stuff, dir),
+			Dir:  filepath.Join(stuff.NestingEscape(), dir),
+		})
+	}
+	return modules.GoMod(modules.Path(stuff, modules.MethodDir(stuff, stuff.Service, stuff.Method)), goVersion, replaces...)
+}
+
 // execute a template to produce a string.
 func execToString(t *template.Template, opt interface{}) (string, error) {
 	buf := &bytes.Buffer{}
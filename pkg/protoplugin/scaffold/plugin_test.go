@@ -0,0 +1,159 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/parameter"
+)
+
+func TestGenerateExisting(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		wantRewrite bool
+	}{{
+		name: "same signature",
+		src: `package unary
+
+import (
+	"context"
+
+	pb "example.com/sample/gen/proto"
+)
+
+func Impl(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return &pb.Response{}, nil
+}
+`,
+	}, {
+		name: "another import alias",
+		src: `package unary
+
+import (
+	stdctx "context"
+
+	samplepb "example.com/sample/gen/proto"
+)
+
+func Impl(ctx stdctx.Context, req *samplepb.Request) (*samplepb.Response, error) {
+	return &samplepb.Response{}, nil
+}
+`,
+	}, {
+		name: "another package by the same alias",
+		src: `package unary
+
+import (
+	"context"
+
+	pb "example.com/other/gen/proto"
+)
+
+func Impl(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	return &pb.Response{}, nil
+}
+`,
+		wantRewrite: true,
+	}, {
+		name: "changed type",
+		src: `package unary
+
+import (
+	"context"
+
+	samplepb "example.com/sample/gen/proto"
+)
+
+func Impl(ctx context.Context, req *samplepb.Request) (*samplepb.Request, error) {
+	return req, nil
+}
+`,
+		wantRewrite: true,
+	}}
+
+	fd := &descriptor.FileDescriptorProto{
+		Name:    proto.String("service.proto"),
+		Package: proto.String("sample"),
+		MessageType: []*descriptor.DescriptorProto{
+			{Name: proto.String("Request")},
+			{Name: proto.String("Response")},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("SampleService"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Unary"),
+				InputType:  proto.String(".sample.Request"),
+				OutputType: proto.String(".sample.Response"),
+			}},
+		}},
+		Options: &descriptor.FileOptions{
+			GoPackage: proto.String("example.com/sample/gen/proto;sample"),
+		},
+		Syntax: proto.String("proto3"),
+	}
+	request := &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.GetName()},
+		ProtoFile:      []*descriptor.FileDescriptorProto{fd},
+	}
+	stuff := &parameter.Stuff{
+		Name:       "scaffold",
+		Base:       "example.com/sample",
+		GenDir:     "gen",
+		MethodsDir: "pkg/methods",
+		Service:    "SampleService",
+		Method:     "Unary",
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "scaffold")
+			if err != nil {
+				t.Fatalf("TempDir() = %v", err)
+			}
+			defer os.RemoveAll(dir)
+			// The tests have already been scaffolded.
+			for name, src := range map[string]string{"main.go": test.src, "main_test.go": "package unary\n"} {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+					t.Fatalf("WriteFile() = %v", err)
+				}
+			}
+
+			resp, err := Generate(stuff, request, dir)
+			if err != nil {
+				t.Fatalf("Generate() = %v", err)
+			}
+			var rewritten bool
+			for _, f := range resp.File {
+				if f.GetName() == "main.go" {
+					rewritten = true
+				} else {
+					t.Errorf("Generate() wrote %s", f.GetName())
+				}
+			}
+			if rewritten != test.wantRewrite {
+				t.Errorf("Generate() rewrote main.go: %v, wanted %v", rewritten, test.wantRewrite)
+			}
+		})
+	}
+}
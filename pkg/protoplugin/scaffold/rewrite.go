@@ -0,0 +1,279 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// existing is a method's current implementation of Impl.
+type existing struct {
+	filename string
+	src      []byte
+	fset     *token.FileSet
+	file     *ast.File
+	decl     *ast.FuncDecl
+}
+
+// findImpl looks for the receiverless Impl function among the Go files in
+// dir, returning nil if there is none.
+func findImpl(dir string) (*existing, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range files {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == "Impl" {
+				return &existing{filename: filename, src: src, fset: fset, file: f, decl: fd}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// exists reports whether the file exists.
+func exists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// parseFunc parses the text of a single function declaration.
+func parseFunc(text string) (*ast.FuncDecl, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package scaffold\n"+text, 0)
+	if err != nil {
+		return nil, err
+	}
+	return f.Decls[0].(*ast.FuncDecl), nil
+}
+
+// scope returns the import paths of the packages that the file of the
+// existing Impl imports, by the names under which it imports them.
+func (e *existing) scope() map[string]string {
+	names := make(map[string]string)
+	for _, spec := range e.file.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		names[path] = nameOf(spec)
+	}
+	return scope(names)
+}
+
+// scope inverts names, which maps import paths to the names under which
+// they are imported (or "" for the package's own name).
+func scope(names map[string]string) map[string]string {
+	paths := make(map[string]string, len(names))
+	for path, name := range names {
+		if name == "" {
+			name = packageName(path)
+		}
+		paths[name] = path
+	}
+	return paths
+}
+
+// majorVersion matches the last element of the import path of a module's
+// major version, which isn't the name of its package.
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// packageName guesses the name of the package at path, e.g. yaml for
+// gopkg.in/yaml.v2 and foo for example.com/foo/v2.
+func packageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && majorVersion.MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// signature renders the parameter and result types of a function, ignoring
+// their names, so that renaming parameters doesn't count as a change.  The
+// packages that qualify the types are rendered by their import paths, found
+// in paths by the names in scope, so neither does renaming an import.
+func signature(ft *ast.FuncType, paths map[string]string) string {
+	var parts []string
+	for _, fl := range []*ast.FieldList{ft.Params, ft.Results} {
+		var types []string
+		if fl != nil {
+			for _, field := range fl.List {
+				buf := &bytes.Buffer{}
+				printer.Fprint(buf, token.NewFileSet(), qualify(field.Type, paths))
+				n := len(field.Names)
+				if n == 0 {
+					n = 1
+				}
+				for i := 0; i < n; i++ {
+					types = append(types, buf.String())
+				}
+			}
+		}
+		parts = append(parts, "("+strings.Join(types, ", ")+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// qualify returns a copy of the type expr, with the names of the packages
+// that qualify its types replaced by their (quoted) import paths.
+func qualify(expr ast.Expr, paths map[string]string) ast.Expr {
+	// Print and reparse the expression to copy it.
+	buf := &bytes.Buffer{}
+	printer.Fprint(buf, token.NewFileSet(), expr)
+	c, err := parser.ParseExpr(buf.String())
+	if err != nil {
+		return expr
+	}
+	ast.Inspect(c, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if path, ok := paths[id.Name]; ok {
+					id.Name = strconv.Quote(path)
+				}
+			}
+		}
+		return true
+	})
+	return c
+}
+
+// rewrite replaces the existing Impl with fn, placing the previous body into
+// a comment, and fixes up the imports.  imports maps the import paths that fn
+// requires to their names.
+func (e *existing) rewrite(fn string, imports map[string]string) ([]byte, error) {
+	start := e.fset.Position(e.decl.Pos()).Offset
+	end := e.fset.Position(e.decl.End()).Offset
+	lbrace := e.fset.Position(e.decl.Body.Lbrace).Offset
+	rbrace := e.fset.Position(e.decl.Body.Rbrace).Offset
+
+	// The names the previous body selected from, e.g. "fmt" in fmt.Errorf.
+	oldNames := make(map[string]struct{})
+	ast.Inspect(e.decl.Body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				oldNames[id.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+
+	comment := []string{
+		"",
+		"\t// TODO: The signature of Impl changed, so its previous body is below.",
+		"\t//",
+	}
+	for _, line := range strings.Split(strings.Trim(string(e.src[lbrace+1:rbrace]), "\n"), "\n") {
+		comment = append(comment, "\t//"+line)
+	}
+	fn = strings.Replace(fn, "{\n", "{"+strings.Join(comment, "\n")+"\n\n", 1)
+
+	src := string(e.src[:start]) + strings.TrimSpace(fn) + string(e.src[end:])
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, e.filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for path, name := range imports {
+		astutil.AddNamedImport(fset, f, name, path)
+	}
+	// Not every signature needs every import, e.g. the proto package when
+	// both types come from elsewhere.
+	for path, name := range imports {
+		if !astutil.UsesImport(f, path) {
+			astutil.DeleteNamedImport(fset, f, name, path)
+		}
+	}
+	// Drop the imports that only the previous body used.  Package names are
+	// guessed from import paths, so we only consider names it selected from.
+	var unused []*ast.ImportSpec
+	for _, spec := range f.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if _, ok := oldNames[name]; ok && !astutil.UsesImport(f, path) {
+			unused = append(unused, spec)
+		}
+	}
+	for _, spec := range unused {
+		astutil.DeleteNamedImport(fset, f, nameOf(spec), strings.Trim(spec.Path.Value, `"`))
+	}
+
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func nameOf(spec *ast.ImportSpec) string {
+	if spec.Name == nil {
+		return ""
+	}
+	return spec.Name.Name
+}
+
+// dropUnused removes those of the given imports from src that nothing uses.
+func dropUnused(filename, src string, paths ...string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range paths {
+		if astutil.UsesImport(f, path) {
+			continue
+		}
+		for _, spec := range f.Imports {
+			if strings.Trim(spec.Path.Value, `"`) == path {
+				astutil.DeleteNamedImport(fset, f, nameOf(spec), path)
+				break
+			}
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, fset, f); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -16,12 +16,48 @@ package scaffold
 
 import (
 	"text/template"
+
+	"github.com/mattmoor/korpc/pkg/gotypes"
+	"github.com/mattmoor/korpc/pkg/templates"
 )
 
+// templateName is the file from which projects may override scaffoldTemplate.
+const templateName = "scaffold.go.tmpl"
+
+// options is the data with which a method's main.go is scaffolded.
 type options struct {
-	Package         string
+	// Package is the name of the method's package.
+	Package string
+	// ProtoImportPath is the import path of the generated proto package.
 	ProtoImportPath string
-	Body            string
+	// Imports are needed to name the request and response types in Body.
+	Imports []gotypes.Import
+	// Body is the source of the unimplemented Impl function.
+	Body string
+}
+
+// testOptions is the data with which a method's main_test.go is scaffolded.
+// The test templates are not registered, so projects can't override them:
+// main_test.go is only scaffolded once, after which it is theirs to edit.
+type testOptions struct {
+	Package           string
+	Service           string
+	Method            string
+	ProtoImportPath   string
+	ImplImportPath    string
+	AdapterImportPath string
+	// StreamImportPath is the import path of the stream package that the
+	// entrypoint generates under stream_style: STREAM, and is otherwise empty.
+	StreamImportPath string
+	Imports          []gotypes.Import
+	RequestType      string
+	ResponseType     string
+	ClientStreaming  bool
+	ServerStreaming  bool
+	Streaming        bool
+	Channels         bool
+	Unimplemented    bool
+	Body             string
 }
 
 const (
@@ -29,21 +65,20 @@ const (
 
 import (
 	"context"
-	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "{{.ProtoImportPath}}"
-)
+{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
 
 {{.Body}}
 `
 
-	// TODO(mattmoor): Right now these assume that the request/response types
-	// come from the same proto where the method is defined.  While this doesn't
-	// seem an outrageous assumption for early prototyping, it is something a
-	// proper solution would address.
-	unaryErrorBody = "return nil, errors.New(`{{.}}`)"
+	unaryErrorBody = "return nil, status.Error(codes.Unimplemented, `{{.}}`)"
 	unarySkeleton  = `
-func {{.Receiver}}{{.Name}}(ctx context.Context, req *pb.{{.RequestType}}) (*pb.{{.ResponseType}}, error) {
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, req *{{.RequestType}}) (*{{.ResponseType}}, error) {
 	{{.Body}}
 }
 `
@@ -52,7 +87,7 @@ func {{.Receiver}}{{.Name}}(ctx context.Context, req *pb.{{.RequestType}}) (*pb.
 		select {
 		case _, ok := <-req:
 			if !ok {
-				return errors.New("{{.}}")
+				return status.Error(codes.Unimplemented, "{{.}}")
 			}
 		}
 	}
@@ -62,31 +97,495 @@ func {{.Receiver}}{{.Name}}(ctx context.Context, req *pb.{{.RequestType}}) (*pb.
 		select {
 		case _, ok := <-req:
 			if !ok {
-				return nil, errors.New("{{.}}")
+				return nil, status.Error(codes.Unimplemented, "{{.}}")
 			}
 		}
 	}
 `
-	streamOutErrorBody  = "return errors.New(`{{.}}`)"
+	streamOutErrorBody  = "return status.Error(codes.Unimplemented, `{{.}}`)"
 	streamInOutSkeleton = `
-func {{.Receiver}}{{.Name}}(ctx context.Context, req <-chan *pb.{{.RequestType}}, resp chan *pb.{{.ResponseType}}) error {
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, req <-chan *{{.RequestType}}, resp chan *{{.ResponseType}}) error {
 	{{.Body}}
 }
 `
 	streamInSkeleton = `
-func {{.Receiver}}{{.Name}}(ctx context.Context, req <-chan *pb.{{.RequestType}}) (*pb.{{.ResponseType}}, error) {
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, req <-chan *{{.RequestType}}) (*{{.ResponseType}}, error) {
 	{{.Body}}
 }
 `
 	streamOutSkeleton = `
-func {{.Receiver}}{{.Name}}(ctx context.Context, req *pb.{{.RequestType}}, resp chan *pb.{{.ResponseType}}) error {
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, req *{{.RequestType}}, resp chan *{{.ResponseType}}) error {
+	{{.Body}}
+}
+`
+
+	// The skeletons of methods with stream_style: STREAM, which are passed
+	// the interface that the entrypoint generates in its stream package.
+	streamInOutStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, srv stream.Server) error {
+	{{.Body}}
+}
+`
+	streamInStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, srv stream.Server) (*{{.ResponseType}}, error) {
+	{{.Body}}
+}
+`
+	streamOutStreamSkeleton = `
+{{.Doc}}func {{.Receiver}}{{.Name}}(ctx context.Context, req *{{.RequestType}}, srv stream.Server) error {
 	{{.Body}}
 }
+`
+
+	testTemplate = `package {{.Package}}_test
+
+import (
+	"context"
+{{if .Streaming}}	"io"
+{{end}}	"net"
+	"testing"
+{{if .Channels}}	"time"
+{{end}}
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+{{if .StreamImportPath}}	"google.golang.org/grpc/metadata"
+{{end}}	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mattmoor/korpc/pkg/runtime/errors"
+
+	"{{.AdapterImportPath}}"
+{{if .StreamImportPath}}	"{{.StreamImportPath}}"
+{{end}}	pb "{{.ProtoImportPath}}"
+	impl "{{.ImplImportPath}}"
+{{range .Imports}}	{{.Alias}} "{{.Path}}"
+{{end}})
+{{.Body}}
+// dial serves the generated adapter over an in-memory connection, and returns
+// a client for it.
+func dial(t *testing.T) pb.{{.Service}}Client {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(errors.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(errors.StreamServerInterceptor()))
+	pb.Register{{.Service}}Server(s, &adapter.Server{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("DialContext() = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.New{{.Service}}Client(conn)
+}
+
+// code returns the code of the status that err is served with.
+func code(err error) codes.Code {
+	return errors.Status(err).Code()
+}
+{{if .StreamImportPath}}
+// fakeStream is an in-memory stream.Server, which passes Impl its requests
+// and records what Impl sends.
+type fakeStream struct {
+{{if .ClientStreaming}}	reqs    []*{{.RequestType}}
+{{end}}{{if .ServerStreaming}}	sent    []*{{.ResponseType}}
+{{end}}	header  metadata.MD
+	trailer metadata.MD
+}
+
+var _ stream.Server = (*fakeStream)(nil)
+{{if .ClientStreaming}}
+func (s *fakeStream) Recv() (*{{.RequestType}}, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+{{end}}{{if .ServerStreaming}}
+func (s *fakeStream) Send(resp *{{.ResponseType}}) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+{{end}}
+func (s *fakeStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *fakeStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+{{end}}`
+
+	unaryTestBody = `
+// cases are run both against Impl and through the generated adapter, so add
+// one for each behavior of {{.Method}}.
+var cases = []struct {
+	name string
+	req  *{{.RequestType}}
+	want *{{.ResponseType}}
+	code codes.Code
+}{
+{{if .Unimplemented}}	{
+		name: "unimplemented",
+		req:  &{{.RequestType}}{},
+		code: codes.Unimplemented,
+	},
+{{else}}	// TODO: Add a case for each behavior of {{.Method}}.
+{{end}}}
+
+func TestImpl(t *testing.T) {
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := impl.Impl(context.Background(), test.req)
+			if c := code(err); c != test.code {
+				t.Fatalf("Impl() = %v, wanted code %v", err, test.code)
+			}
+			if !proto.Equal(got, test.want) {
+				t.Errorf("Impl() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	client := dial(t)
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := client.{{.Method}}(context.Background(), test.req)
+			if c := code(err); c != test.code {
+				t.Fatalf("{{.Method}}() = %v, wanted code %v", err, test.code)
+			}
+			if !proto.Equal(got, test.want) {
+				t.Errorf("{{.Method}}() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+`
+
+	streamInTestBody = `
+// cases are run both against Impl and through the generated adapter, so add
+// one for each behavior of {{.Method}}.
+var cases = []struct {
+	name string
+	reqs []*{{.RequestType}}
+	want *{{.ResponseType}}
+	code codes.Code
+}{
+{{if .Unimplemented}}	{
+		name: "unimplemented",
+		reqs: []*{{.RequestType}}{&{{.RequestType}}{}},
+		code: codes.Unimplemented,
+	},
+{{else}}	// TODO: Add a case for each behavior of {{.Method}}.
+{{end}}}
+
+func TestImpl(t *testing.T) {
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := impl.Impl(context.Background(), {{if .Channels}}send(test.reqs){{else}}&fakeStream{reqs: test.reqs}{{end}})
+			if c := code(err); c != test.code {
+				t.Fatalf("Impl() = %v, wanted code %v", err, test.code)
+			}
+			if !proto.Equal(got, test.want) {
+				t.Errorf("Impl() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+{{if .Channels}}// When the client goes away the adapter cancels the context and closes the
+// requests, and Impl must return.
+func TestImplCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		impl.Impl(ctx, send([]*{{.RequestType}}{&{{.RequestType}}{}}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Impl() did not return after cancellation")
+	}
+}
+
+{{end}}func TestServer(t *testing.T) {
+	client := dial(t)
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := client.{{.Method}}(context.Background())
+			if err != nil {
+				t.Fatalf("{{.Method}}() = %v", err)
+			}
+			for _, req := range test.reqs {
+				if err := stream.Send(req); err != nil {
+					// The server ended the stream, and CloseAndRecv returns why.
+					break
+				}
+			}
+			got, err := stream.CloseAndRecv()
+			if c := code(err); c != test.code {
+				t.Fatalf("CloseAndRecv() = %v, wanted code %v", err, test.code)
+			}
+			if !proto.Equal(got, test.want) {
+				t.Errorf("CloseAndRecv() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+{{if .Channels}}
+// send returns the requests as the adapter passes them to Impl, on a channel
+// that is closed once the client closes its side of the stream.
+func send(reqs []*{{.RequestType}}) <-chan *{{.RequestType}} {
+	input := make(chan *{{.RequestType}}, len(reqs))
+	for _, req := range reqs {
+		input <- req
+	}
+	close(input)
+	return input
+}
+{{end}}
+// Don't complain about the import
+var _ = io.EOF
+`
+
+	streamOutTestBody = `
+// cases are run both against Impl and through the generated adapter, so add
+// one for each behavior of {{.Method}}.
+var cases = []struct {
+	name string
+	req  *{{.RequestType}}
+	want []*{{.ResponseType}}
+	code codes.Code
+}{
+{{if .Unimplemented}}	{
+		name: "unimplemented",
+		req:  &{{.RequestType}}{},
+		code: codes.Unimplemented,
+	},
+{{else}}	// TODO: Add a case for each behavior of {{.Method}}.
+{{end}}}
+
+func TestImpl(t *testing.T) {
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+{{if .Channels}}			got, err := receive(func(resp chan *{{.ResponseType}}) error {
+				return impl.Impl(context.Background(), test.req, resp)
+			})
+{{else}}			s := &fakeStream{}
+			err := impl.Impl(context.Background(), test.req, s)
+			got := s.sent
+{{end}}			if c := code(err); c != test.code {
+				t.Fatalf("Impl() = %v, wanted code %v", err, test.code)
+			}
+			if !equal(got, test.want) {
+				t.Errorf("Impl() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+{{if .Channels}}// When the client goes away the adapter cancels the context and stops
+// reading responses, and Impl must return rather than block sending them.
+func TestImplCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		impl.Impl(ctx, &{{.RequestType}}{}, make(chan *{{.ResponseType}}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Impl() did not return after cancellation")
+	}
+}
+
+{{end}}func TestServer(t *testing.T) {
+	client := dial(t)
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := client.{{.Method}}(context.Background(), test.req)
+			if err != nil {
+				t.Fatalf("{{.Method}}() = %v", err)
+			}
+			got, err := recvAll(stream)
+			if c := code(err); c != test.code {
+				t.Fatalf("Recv() = %v, wanted code %v", err, test.code)
+			}
+			if !equal(got, test.want) {
+				t.Errorf("Recv() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+` + streamTestHelpers
+
+	streamInOutTestBody = `
+// cases are run both against Impl and through the generated adapter, so add
+// one for each behavior of {{.Method}}.
+var cases = []struct {
+	name string
+	reqs []*{{.RequestType}}
+	want []*{{.ResponseType}}
+	code codes.Code
+}{
+{{if .Unimplemented}}	{
+		name: "unimplemented",
+		reqs: []*{{.RequestType}}{&{{.RequestType}}{}},
+		code: codes.Unimplemented,
+	},
+{{else}}	// TODO: Add a case for each behavior of {{.Method}}.
+{{end}}}
+
+func TestImpl(t *testing.T) {
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+{{if .Channels}}			got, err := receive(func(resp chan *{{.ResponseType}}) error {
+				return impl.Impl(context.Background(), send(test.reqs), resp)
+			})
+{{else}}			s := &fakeStream{reqs: test.reqs}
+			err := impl.Impl(context.Background(), s)
+			got := s.sent
+{{end}}			if c := code(err); c != test.code {
+				t.Fatalf("Impl() = %v, wanted code %v", err, test.code)
+			}
+			if !equal(got, test.want) {
+				t.Errorf("Impl() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+
+{{if .Channels}}// When the client goes away the adapter cancels the context, closes the
+// requests and stops reading responses, and Impl must return rather than
+// block sending them.
+func TestImplCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		impl.Impl(ctx, send([]*{{.RequestType}}{&{{.RequestType}}{}}), make(chan *{{.ResponseType}}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Impl() did not return after cancellation")
+	}
+}
+
+{{end}}func TestServer(t *testing.T) {
+	client := dial(t)
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			stream, err := client.{{.Method}}(context.Background())
+			if err != nil {
+				t.Fatalf("{{.Method}}() = %v", err)
+			}
+			go func() {
+				for _, req := range test.reqs {
+					if err := stream.Send(req); err != nil {
+						// The server ended the stream, and Recv returns why.
+						break
+					}
+				}
+				stream.CloseSend()
+			}()
+			got, err := recvAll(stream)
+			if c := code(err); c != test.code {
+				t.Fatalf("Recv() = %v, wanted code %v", err, test.code)
+			}
+			if !equal(got, test.want) {
+				t.Errorf("Recv() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
+{{if .Channels}}
+// send returns the requests as the adapter passes them to Impl, on a channel
+// that is closed once the client closes its side of the stream.
+func send(reqs []*{{.RequestType}}) <-chan *{{.RequestType}} {
+	input := make(chan *{{.RequestType}}, len(reqs))
+	for _, req := range reqs {
+		input <- req
+	}
+	close(input)
+	return input
+}
+{{end}}` + streamTestHelpers
+
+	streamTestHelpers = `
+{{if .Channels}}// receive runs Impl as the adapter does, and returns what it sent.  The
+// adapter closes the channel of responses once Impl returns, so Impl must
+// not close it.
+func receive(run func(chan *{{.ResponseType}}) error) ([]*{{.ResponseType}}, error) {
+	resp := make(chan *{{.ResponseType}})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(resp)
+		errCh <- run(resp)
+	}()
+
+	var got []*{{.ResponseType}}
+	for r := range resp {
+		got = append(got, r)
+	}
+	return got, <-errCh
+}
+
+{{end}}// recvAll reads responses until the server ends the stream.
+func recvAll(stream interface {
+	Recv() (*{{.ResponseType}}, error)
+}) ([]*{{.ResponseType}}, error) {
+	var got []*{{.ResponseType}}
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			return got, nil
+		}
+		if err != nil {
+			return got, err
+		}
+		got = append(got, r)
+	}
+}
+
+func equal(got, want []*{{.ResponseType}}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !proto.Equal(got[i], want[i]) {
+			return false
+		}
+	}
+	return true
+}
 `
 )
 
 var (
-	tmpl              = template.Must(template.New("scaffold").Parse(scaffoldTemplate))
 	UnaryMethod       = template.Must(template.New("unary").Parse(unarySkeleton))
 	StreamInOutMethod = template.Must(template.New("stream-in-out").Parse(streamInOutSkeleton))
 	StreamInMethod    = template.Must(template.New("stream-in").Parse(streamInSkeleton))
@@ -95,4 +594,18 @@ var (
 	StreamInOutError  = template.Must(template.New("stream-in-out-error").Parse(streamInOutErrorBody))
 	StreamInError     = template.Must(template.New("stream-in-error").Parse(streamInErrorBody))
 	StreamOutError    = template.Must(template.New("stream-out-error").Parse(streamOutErrorBody))
+
+	streamInOutStreamMethod = template.Must(template.New("stream-in-out-stream").Parse(streamInOutStreamSkeleton))
+	streamInStreamMethod    = template.Must(template.New("stream-in-stream").Parse(streamInStreamSkeleton))
+	streamOutStreamMethod   = template.Must(template.New("stream-out-stream").Parse(streamOutStreamSkeleton))
+
+	testTmpl        = template.Must(template.New("test").Parse(testTemplate))
+	unaryTest       = template.Must(template.New("unary-test").Parse(unaryTestBody))
+	streamInOutTest = template.Must(template.New("stream-in-out-test").Parse(streamInOutTestBody))
+	streamInTest    = template.Must(template.New("stream-in-test").Parse(streamInTestBody))
+	streamOutTest   = template.Must(template.New("stream-out-test").Parse(streamOutTestBody))
 )
+
+func init() {
+	templates.Register(templateName, scaffoldTemplate)
+}
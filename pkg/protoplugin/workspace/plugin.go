@@ -0,0 +1,107 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/protoc-gen-go/plugin"
+
+	"github.com/mattmoor/korpc/pkg/defaults"
+	"github.com/mattmoor/korpc/pkg/modules"
+	"github.com/mattmoor/korpc/pkg/parameter"
+	"github.com/mattmoor/korpc/pkg/protoplugin"
+)
+
+type plugin struct {
+}
+
+var _ protoplugin.Interface = (*plugin)(nil)
+
+// Do lays out the go.work that stitches together the modules of the
+// repository for local development, along with the go.mod of the generated
+// proto package.  The go.mod files of the methods and their entrypoints are
+// created alongside them by the scaffold and entrypoint plugins.
+func (p *plugin) Do(stuff *parameter.Stuff, request *plugin_go.CodeGeneratorRequest) (*plugin_go.CodeGeneratorResponse, error) {
+	var resp plugin_go.CodeGeneratorResponse
+	if !stuff.Modules {
+		return &resp, nil
+	}
+
+	codegen := make(map[string]struct{})
+	for _, file := range request.FileToGenerate {
+		codegen[file] = struct{}{}
+	}
+
+	// The root module still holds everything but the methods, e.g. the
+	// clients under gen/api.
+	dirs := []string{".", modules.ProtoDir(stuff)}
+	for _, fd := range request.ProtoFile {
+		if _, ok := codegen[fd.GetName()]; !ok {
+			continue
+		}
+		for _, sdp := range fd.Service {
+			for _, mdp := range sdp.Method {
+				dirs = append(dirs, modules.EntrypointDir(stuff, sdp.GetName(), mdp.GetName()))
+				// Methods implemented elsewhere have no module here.
+				if defaults.Options(mdp).ImplImportPath == "" {
+					dirs = append(dirs, modules.MethodDir(stuff, sdp.GetName(), mdp.GetName()))
+				}
+			}
+		}
+	}
+	for i, dir := range dirs {
+		// The directories of go.work are relative paths, which start with ./
+		if dir != "." {
+			dirs[i] = "./" + filepath.ToSlash(dir)
+		}
+	}
+
+	// We are run from the root of the repository.
+	goVersion, err := modules.GoVersion(".")
+	if err != nil {
+		return nil, err
+	}
+
+	workName := "go.work"
+	workContent, err := modules.GoWork(goVersion, dirs)
+	if err != nil {
+		return nil, err
+	}
+	resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+		Name:    &workName,
+		Content: &workContent,
+	})
+
+	// The go.mod is only created once, after which `go mod tidy` maintains
+	// its requirements.
+	modName := filepath.Join(modules.ProtoDir(stuff), "go.mod")
+	if _, err := os.Stat(modName); os.IsNotExist(err) {
+		modContent, err := modules.GoMod(modules.Path(stuff, modules.ProtoDir(stuff)), goVersion)
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, &plugin_go.CodeGeneratorResponse_File{
+			Name:    &modName,
+			Content: &modContent,
+		})
+	}
+	return &resp, nil
+}
+
+func init() {
+	protoplugin.Register("workspace", &plugin{})
+}
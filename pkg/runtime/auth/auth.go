@@ -0,0 +1,226 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth authenticates the bearer tokens presented to korpc
+// entrypoints, and makes their verified claims available to Impl.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mattmoor/korpc/pkg/runtime/internal/health"
+)
+
+// Config holds the auth settings with which an entrypoint is generated.
+type Config struct {
+	// Issuer is the "iss" that tokens must have, if set.
+	Issuer string
+
+	// Audiences are the "aud" values, one of which tokens must have, if set.
+	Audiences []string
+
+	// JWKSFile is the path of a mounted JSON Web Key Set.
+	JWKSFile string
+
+	// JWKSURL is the URL of a JSON Web Key Set, used when JWKSFile is unset.
+	JWKSURL string
+
+	// RequiredScopes are the scopes that tokens must all have.
+	RequiredScopes []string
+}
+
+// Claims are the verified claims of the caller's token.
+type Claims map[string]interface{}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Scopes returns the scopes granted by the "scope" (space-separated) or
+// "scp" (string or list) claims.
+func (c Claims) Scopes() []string {
+	var scopes []string
+	for _, name := range []string{"scope", "scp"} {
+		switch v := c[name].(type) {
+		case string:
+			scopes = append(scopes, strings.Fields(v)...)
+		case []interface{}:
+			for _, s := range v {
+				if s, ok := s.(string); ok {
+					scopes = append(scopes, s)
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a context carrying the given claims.
+func WithClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// ClaimsFromContext returns the verified claims of the caller, or nil if
+// the method does not require authentication.
+func ClaimsFromContext(ctx context.Context) Claims {
+	c, _ := ctx.Value(claimsKey{}).(Claims)
+	return c
+}
+
+// Authenticator verifies bearer tokens against a Config.
+type Authenticator struct {
+	cfg    Config
+	keys   *keySet
+	parser *jwt.Parser
+}
+
+// New returns an Authenticator for the given Config.  The key set is loaded
+// on first use, and reloaded when tokens are signed with unknown keys.
+func New(cfg Config) (*Authenticator, error) {
+	var load func() ([]byte, error)
+	switch {
+	case cfg.JWKSFile != "":
+		load = fileLoader(cfg.JWKSFile)
+	case cfg.JWKSURL != "":
+		load = urlLoader(cfg.JWKSURL)
+	default:
+		return nil, errors.New("auth requires either jwks_file or jwks_url")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	return &Authenticator{
+		cfg:    cfg,
+		keys:   newKeySet(load),
+		parser: jwt.NewParser(opts...),
+	}, nil
+}
+
+// Authenticate verifies the bearer token in the incoming metadata of ctx,
+// returning its claims.  The error is Unauthenticated when the token is
+// missing or invalid, and PermissionDenied when it lacks required scopes.
+func (a *Authenticator) Authenticate(ctx context.Context) (Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var raw string
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			raw = v[7:]
+			break
+		}
+	}
+	if raw == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(kid)
+	}); err != nil {
+		var le *loadError
+		if errors.As(err, &le) {
+			return nil, status.Error(codes.Unavailable, le.Error())
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "invalid bearer token: %v", err)
+	}
+
+	if len(a.cfg.Audiences) > 0 {
+		aud, err := claims.GetAudience()
+		if err != nil || !intersects(aud, a.cfg.Audiences) {
+			return nil, status.Error(codes.Unauthenticated, "invalid bearer token: unexpected audience")
+		}
+	}
+
+	c := Claims(claims)
+	granted := make(map[string]struct{})
+	for _, s := range c.Scopes() {
+		granted[s] = struct{}{}
+	}
+	for _, s := range a.cfg.RequiredScopes {
+		if _, ok := granted[s]; !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "missing required scope %q", s)
+		}
+	}
+	return c, nil
+}
+
+// UnaryServerInterceptor rejects unauthenticated requests, and places the
+// caller's claims into the context of the rest.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if health.IsCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		c, err := a.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(WithClaims(ctx, c), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if health.IsCheck(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		c, err := a.Authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &claimsStream{ServerStream: ss, ctx: WithClaims(ss.Context(), c)})
+	}
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type claimsStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+var _ grpc.ServerStream = (*claimsStream)(nil)
+
+// Context implements grpc.ServerStream
+func (cs *claimsStream) Context() context.Context {
+	return cs.ctx
+}
@@ -0,0 +1,348 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// jwksServer serves the public halves of its keys as a JSON Web Key Set.
+type jwksServer struct {
+	*httptest.Server
+
+	m       sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	fail    bool
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	js := &jwksServer{keys: make(map[string]*rsa.PrivateKey)}
+	js.Server = httptest.NewServer(http.HandlerFunc(js.serveHTTP))
+	t.Cleanup(js.Close)
+	return js
+}
+
+func (js *jwksServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	js.m.Lock()
+	defer js.m.Unlock()
+	js.fetches++
+	if js.fail {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, k := range js.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kid: kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+// rotate adds a new signing key with the given ID.
+func (js *jwksServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() = %v", err)
+	}
+	js.m.Lock()
+	defer js.m.Unlock()
+	js.keys[kid] = k
+	return k
+}
+
+func (js *jwksServer) setFail(fail bool) {
+	js.m.Lock()
+	defer js.m.Unlock()
+	js.fail = fail
+}
+
+func (js *jwksServer) fetchCount() int {
+	js.m.Lock()
+	defer js.m.Unlock()
+	return js.fetches
+}
+
+// sign returns a token with the given claims, signed by key with the kid.
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() = %v", err)
+	}
+	return s
+}
+
+func withToken(raw string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+raw))
+}
+
+// valid returns the claims of a token that the config in TestAuthenticate
+// accepts, to which each test makes a change.
+func valid() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   "https://issuer.example.com",
+		"aud":   []string{"sampleservice"},
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "read write",
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	js := newJWKSServer(t)
+	key := js.rotate(t, "key-1")
+
+	a, err := New(Config{
+		Issuer:         "https://issuer.example.com",
+		Audiences:      []string{"sampleservice"},
+		JWKSURL:        js.URL,
+		RequiredScopes: []string{"read"},
+	})
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+
+	// An unsigned token, and one signed with a shared secret, which here is
+	// the serialized public key as an attacker would use.
+	none := func() string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatalf("SignedString() = %v", err)
+		}
+		return s
+	}
+	hs256 := func() string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+		tok.Header["kid"] = "key-1"
+		s, err := tok.SignedString(key.PublicKey.N.Bytes())
+		if err != nil {
+			t.Fatalf("SignedString() = %v", err)
+		}
+		return s
+	}
+	with := func(k string, v interface{}) jwt.MapClaims {
+		c := valid()
+		c[k] = v
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		want  codes.Code
+		check func(*testing.T, Claims)
+	}{{
+		name: "valid",
+		ctx:  withToken(sign(t, key, "key-1", valid())),
+		want: codes.OK,
+		check: func(t *testing.T, c Claims) {
+			if got, want := c.Subject(), "alice"; got != want {
+				t.Errorf("Subject() = %s, wanted %s", got, want)
+			}
+		},
+	}, {
+		name: "missing token",
+		ctx:  context.Background(),
+		want: codes.Unauthenticated,
+	}, {
+		name: "not a bearer token",
+		ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic YWxpY2U6c2VjcmV0")),
+		want: codes.Unauthenticated,
+	}, {
+		name: "expired",
+		ctx:  withToken(sign(t, key, "key-1", with("exp", time.Now().Add(-time.Minute).Unix()))),
+		want: codes.Unauthenticated,
+	}, {
+		name: "without expiry",
+		ctx: withToken(sign(t, key, "key-1", func() jwt.MapClaims {
+			c := valid()
+			delete(c, "exp")
+			return c
+		}())),
+		want: codes.Unauthenticated,
+	}, {
+		name: "wrong issuer",
+		ctx:  withToken(sign(t, key, "key-1", with("iss", "https://evil.example.com"))),
+		want: codes.Unauthenticated,
+	}, {
+		name: "wrong audience",
+		ctx:  withToken(sign(t, key, "key-1", with("aud", "otherservice"))),
+		want: codes.Unauthenticated,
+	}, {
+		name: "alg none",
+		ctx:  withToken(none()),
+		want: codes.Unauthenticated,
+	}, {
+		name: "HS256",
+		ctx:  withToken(hs256()),
+		want: codes.Unauthenticated,
+	}, {
+		name: "missing scope",
+		ctx:  withToken(sign(t, key, "key-1", with("scope", "write"))),
+		want: codes.PermissionDenied,
+	}, {
+		name: "scp list",
+		ctx:  withToken(sign(t, key, "key-1", with("scp", []string{"read"}))),
+		want: codes.OK,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := a.Authenticate(test.ctx)
+			if got := status.Code(err); got != test.want {
+				t.Fatalf("Authenticate() = %v, wanted %v", err, test.want)
+			}
+			if test.check != nil {
+				test.check(t, c)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRotation(t *testing.T) {
+	defer func(d time.Duration) { minRefreshInterval = d }(minRefreshInterval)
+	minRefreshInterval = 0
+
+	js := newJWKSServer(t)
+	old := js.rotate(t, "key-1")
+	a, err := New(Config{JWKSURL: js.URL})
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+
+	if _, err := a.Authenticate(withToken(sign(t, old, "key-1", valid()))); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if got, want := js.fetchCount(), 1; got != want {
+		t.Errorf("fetches = %d, wanted %d", got, want)
+	}
+
+	// Known keys are served from the cache.
+	if _, err := a.Authenticate(withToken(sign(t, old, "key-1", valid()))); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if got, want := js.fetchCount(), 1; got != want {
+		t.Errorf("fetches = %d, wanted %d", got, want)
+	}
+
+	// Tokens signed with a new key refetch the key set.
+	rotated := js.rotate(t, "key-2")
+	if _, err := a.Authenticate(withToken(sign(t, rotated, "key-2", valid()))); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	if got, want := js.fetchCount(), 2; got != want {
+		t.Errorf("fetches = %d, wanted %d", got, want)
+	}
+
+	// Keys that still aren't in the set are the caller's problem.
+	if _, err := a.Authenticate(withToken(sign(t, rotated, "key-3", valid()))); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Authenticate() = %v, wanted %v", err, codes.Unauthenticated)
+	}
+
+	// Failing to fetch the key set is ours.
+	js.setFail(true)
+	if _, err := a.Authenticate(withToken(sign(t, rotated, "key-4", valid()))); status.Code(err) != codes.Unavailable {
+		t.Errorf("Authenticate() = %v, wanted %v", err, codes.Unavailable)
+	}
+}
+
+func TestAuthenticateRefreshInterval(t *testing.T) {
+	js := newJWKSServer(t)
+	key := js.rotate(t, "key-1")
+	a, err := New(Config{JWKSURL: js.URL})
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+
+	if _, err := a.Authenticate(withToken(sign(t, key, "key-1", valid()))); err != nil {
+		t.Fatalf("Authenticate() = %v", err)
+	}
+	// Unknown keys don't let callers hammer the JWKS endpoint.
+	for i := 0; i < 3; i++ {
+		if _, err := a.Authenticate(withToken(sign(t, key, "unknown", valid()))); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Authenticate() = %v, wanted %v", err, codes.Unauthenticated)
+		}
+	}
+	if got, want := js.fetchCount(), 1; got != want {
+		t.Errorf("fetches = %d, wanted %d", got, want)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	js := newJWKSServer(t)
+	key := js.rotate(t, "key-1")
+	a, err := New(Config{JWKSURL: js.URL})
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	intercept := a.UnaryServerInterceptor()
+
+	var got Claims
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = ClaimsFromContext(ctx)
+		return nil, nil
+	}
+
+	// Health checks are exempt.
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	if _, err := intercept(context.Background(), nil, info, handler); err != nil {
+		t.Errorf("intercept() = %v", err)
+	}
+
+	info = &grpc.UnaryServerInfo{FullMethod: "/sample.SampleService/Unary"}
+	if _, err := intercept(context.Background(), nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("intercept() = %v, wanted %v", err, codes.Unauthenticated)
+	}
+	if _, err := intercept(withToken(sign(t, key, "key-1", valid())), nil, info, handler); err != nil {
+		t.Fatalf("intercept() = %v", err)
+	}
+	if got.Subject() != "alice" {
+		t.Errorf("ClaimsFromContext() = %v, wanted the token's claims", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("New() = nil, wanted an error without a key set")
+	}
+}
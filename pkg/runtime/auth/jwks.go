@@ -0,0 +1,221 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The asymmetric signing algorithms we accept.  Symmetric algorithms are
+// excluded, since JWKS keys are public.
+var validMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// How often the key set may be reloaded to find an unknown key.  This is a
+// variable for testing.
+var minRefreshInterval = time.Minute
+
+// keySet is a JSON Web Key Set, indexed by key ID.
+type keySet struct {
+	load func() ([]byte, error)
+
+	m       sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+}
+
+func newKeySet(load func() ([]byte, error)) *keySet {
+	return &keySet{load: load}
+}
+
+// key returns the public key with the given ID, reloading the key set if it
+// is unknown (e.g. because keys have been rotated).  Tokens without a key ID
+// may be verified when the set holds a single key.
+func (ks *keySet) key(kid string) (interface{}, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if k, ok := ks.lookup(kid); ok {
+		return k, nil
+	}
+	if ks.keys != nil && time.Since(ks.fetched) < minRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	b, err := ks.load()
+	if err != nil {
+		return nil, &loadError{fmt.Errorf("loading JWKS: %v", err)}
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, &loadError{fmt.Errorf("parsing JWKS: %v", err)}
+	}
+	ks.keys, ks.fetched = keys, time.Now()
+
+	if k, ok := ks.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// loadError is returned when the key set cannot be loaded, which is our
+// problem rather than the caller's.
+type loadError struct {
+	error
+}
+
+func (ks *keySet) lookup(kid string) (interface{}, bool) {
+	if k, ok := ks.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+func fileLoader(path string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}
+}
+
+func urlLoader(url string) func() ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status fetching %s: %d", url, resp.StatusCode)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS parses the supported signing keys of a JSON Web Key Set.
+func parseJWKS(b []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Skip keys we cannot verify with, rather than rejecting the set.
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key length %d", len(x))
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
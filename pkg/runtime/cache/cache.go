@@ -0,0 +1,218 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache caches the responses of side-effect-free unary methods in
+// korpc entrypoints.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mattmoor/korpc/pkg/runtime/auth"
+	"github.com/mattmoor/korpc/pkg/runtime/internal/health"
+	"github.com/mattmoor/korpc/pkg/runtime/metrics"
+)
+
+// Config holds the cache settings with which an entrypoint is generated.
+type Config struct {
+	// TTL is how long responses are cached.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of cached responses.
+	MaxEntries int
+
+	// KeyHeaders are the metadata headers whose values are part of the key.
+	KeyHeaders []string
+}
+
+// Cache is a least-recently-used cache of responses, keyed on the canonical
+// encoding of requests.
+type Cache struct {
+	cfg Config
+
+	m       sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List
+}
+
+type entry struct {
+	key     [sha256.Size]byte
+	resp    proto.Message
+	expires time.Time
+}
+
+// New returns a Cache for the given Config.
+func New(cfg Config) *Cache {
+	return &Cache{
+		cfg:     cfg,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// directives are the cache-control directives that clients send as
+// "cache-control" metadata.
+type directives struct {
+	// noCache skips the lookup, but still caches the response.
+	noCache bool
+	// noStore neither looks up nor caches the response.
+	noStore bool
+	// maxAge limits the age of the responses that are acceptable.
+	maxAge time.Duration
+}
+
+func parseDirectives(md metadata.MD) directives {
+	d := directives{maxAge: -1}
+	for _, v := range md.Get("cache-control") {
+		for _, dir := range strings.Split(v, ",") {
+			dir = strings.ToLower(strings.TrimSpace(dir))
+			switch {
+			case dir == "no-cache":
+				d.noCache = true
+			case dir == "no-store":
+				d.noStore = true
+			case strings.HasPrefix(dir, "max-age="):
+				if secs, err := strconv.Atoi(strings.TrimPrefix(dir, "max-age=")); err == nil {
+					d.maxAge = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	}
+	return d
+}
+
+// key hashes the method, the deterministic encoding of the request, the
+// verified identity of the caller (when authenticated) and the values of the
+// key headers.
+func (c *Cache) key(md metadata.MD, claims auth.Claims, fullMethod string, req proto.Message) ([sha256.Size]byte, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(req); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(fullMethod))
+	h.Write([]byte{0})
+	h.Write(buf.Bytes())
+	if claims != nil {
+		iss, _ := claims["iss"].(string)
+		h.Write([]byte{0})
+		h.Write([]byte(iss))
+		h.Write([]byte{0})
+		h.Write([]byte(claims.Subject()))
+	}
+	for _, name := range c.cfg.KeyHeaders {
+		for _, v := range md.Get(name) {
+			h.Write([]byte{0})
+			h.Write([]byte(v))
+		}
+		h.Write([]byte{0})
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key, nil
+}
+
+func (c *Cache) get(key [sha256.Size]byte, now time.Time, maxAge time.Duration) (proto.Message, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if now.After(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	if maxAge >= 0 && now.Sub(e.expires.Add(-c.cfg.TTL)) > maxAge {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return proto.Clone(e.resp), true
+}
+
+func (c *Cache) put(key [sha256.Size]byte, now time.Time, resp proto.Message) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e := &entry{key: key, resp: proto.Clone(resp), expires: now.Add(c.cfg.TTL)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.cfg.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// UnaryServerInterceptor serves cached responses to requests that have been
+// seen within the TTL, and caches successful responses.
+func (c *Cache) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		pm, ok := req.(proto.Message)
+		if !ok || health.IsCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		d := parseDirectives(md)
+		if d.noStore {
+			return handler(ctx, req)
+		}
+		// Responses may vary by caller, so never serve one caller's response
+		// to another.  Without a subject we can't tell callers apart.
+		claims := auth.ClaimsFromContext(ctx)
+		if claims != nil && claims.Subject() == "" {
+			return handler(ctx, req)
+		}
+		key, err := c.key(md, claims, info.FullMethod, pm)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		if !d.noCache {
+			resp, hit := c.get(key, time.Now(), d.maxAge)
+			metrics.RecordCacheLookup(ctx, info.FullMethod, hit)
+			if hit {
+				return resp, nil
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if rm, ok := resp.(proto.Message); ok {
+			c.put(key, time.Now(), rm)
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mattmoor/korpc/pkg/runtime/auth"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	c := New(Config{
+		TTL:        time.Minute,
+		MaxEntries: 10,
+		KeyHeaders: []string{"accept-language"},
+	})
+	intercept := c.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/sample.SampleService/Unary"}
+
+	// The handler responds with how many times it has been called.
+	calls := int64(0)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return &wrappers.Int64Value{Value: calls}, nil
+	}
+	call := func(t *testing.T, ctx context.Context) int64 {
+		t.Helper()
+		resp, err := intercept(ctx, &wrappers.StringValue{Value: "req"}, info, handler)
+		if err != nil {
+			t.Fatalf("intercept() = %v", err)
+		}
+		return resp.(*wrappers.Int64Value).Value
+	}
+
+	bg := context.Background()
+	en := metadata.NewIncomingContext(bg, metadata.Pairs("accept-language", "en"))
+	fr := metadata.NewIncomingContext(bg, metadata.Pairs("accept-language", "fr"))
+	alice := auth.WithClaims(en, auth.Claims{"iss": "https://issuer.example.com", "sub": "alice"})
+	bob := auth.WithClaims(en, auth.Claims{"iss": "https://issuer.example.com", "sub": "bob"})
+	mallory := auth.WithClaims(en, auth.Claims{"iss": "https://evil.example.com", "sub": "alice"})
+	anonymous := auth.WithClaims(en, auth.Claims{"iss": "https://issuer.example.com"})
+	noCache := metadata.NewIncomingContext(bg, metadata.Pairs("accept-language", "en", "cache-control", "no-cache"))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int64
+	}{{
+		name: "miss",
+		ctx:  en,
+		want: 1,
+	}, {
+		name: "hit",
+		ctx:  en,
+		want: 1,
+	}, {
+		name: "key header",
+		ctx:  fr,
+		want: 2,
+	}, {
+		name: "caller",
+		ctx:  alice,
+		want: 3,
+	}, {
+		name: "caller hit",
+		ctx:  alice,
+		want: 3,
+	}, {
+		name: "another caller",
+		ctx:  bob,
+		want: 4,
+	}, {
+		name: "another issuer",
+		ctx:  mallory,
+		want: 5,
+	}, {
+		name: "caller without a subject",
+		ctx:  anonymous,
+		want: 6,
+	}, {
+		name: "caller without a subject isn't cached",
+		ctx:  anonymous,
+		want: 7,
+	}, {
+		name: "no-cache",
+		ctx:  noCache,
+		want: 8,
+	}, {
+		name: "no-cache refreshes",
+		ctx:  en,
+		want: 8,
+	}}
+	for _, test := range tests {
+		if got := call(t, test.ctx); got != test.want {
+			t.Errorf("%s: intercept() = %d, wanted %d", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client dials the Knative Services of sibling korpc methods over
+// the cluster network, forwarding the caller's deadline, trace and an
+// allowlist of its metadata.
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultNamespace is where Knative Services are deployed when the
+// namespace is left unspecified.
+const defaultNamespace = "default"
+
+// forwarded are the incoming headers that are forwarded on outgoing calls
+// by default.  Credentials (e.g. authorization and cookie) are deliberately
+// left out, so that they aren't handed to methods that didn't ask for them.
+var forwarded = []string{
+	// The request id that Istio uses to correlate the access logs of a call.
+	"x-request-id",
+}
+
+// Address returns the cluster-local address of the named Knative Service.
+func Address(name, namespace string) string {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return fmt.Sprintf("%s.%s.svc.cluster.local:80", name, namespace)
+}
+
+// Dial connects to the named Knative Service.  The connection is established
+// lazily, and the provided options take precedence over the defaults.
+func Dial(ctx context.Context, name, namespace string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		// Knative Services serve h2c within the cluster.
+		grpc.WithInsecure(),
+		// Inject the trace context, which the telemetry package configures.
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor()),
+	}, opts...)
+	return grpc.DialContext(ctx, Address(name, namespace), opts...)
+}
+
+// ForwardHeaders returns the DialOptions that also forward the named
+// headers of the incoming request on outgoing calls, e.g. to pass the
+// caller's credentials along to a method that checks them.  Headers that
+// describe the incoming hop itself are never forwarded.
+func ForwardHeaders(keys ...string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unaryInterceptor(keys)),
+		grpc.WithChainStreamInterceptor(streamInterceptor(keys)),
+	}
+}
+
+// UnaryClientInterceptor forwards the allowlisted metadata of the incoming
+// request being served by ctx (if any) on outgoing calls.  Deadlines are
+// carried by ctx itself.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return unaryInterceptor(forwarded)
+}
+
+// StreamClientInterceptor is the streaming counterpart of
+// UnaryClientInterceptor.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return streamInterceptor(forwarded)
+}
+
+func unaryInterceptor(keys []string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(forward(ctx, keys), method, req, reply, cc, opts...)
+	}
+}
+
+func streamInterceptor(keys []string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(forward(ctx, keys), desc, cc, method, opts...)
+	}
+}
+
+// forward copies the named incoming headers onto the outgoing context.
+// Metadata the caller has already set on the outgoing context wins.
+func forward(ctx context.Context, keys []string) context.Context {
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	for _, k := range keys {
+		k = strings.ToLower(k)
+		if hopHeader(k) || len(md.Get(k)) != 0 {
+			continue
+		}
+		if v := in.Get(k); len(v) != 0 {
+			md.Set(k, v...)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// hopHeader reports whether the header is specific to the incoming call.
+// Trace headers are excluded because the client stats handler injects the
+// current span in their place, and the resume token because it names a
+// position in the incoming stream rather than in any we open.
+func hopHeader(k string) bool {
+	switch k {
+	case ":authority", "content-type", "user-agent", "te", "traceparent", "tracestate", "b3",
+		"korpc-resume-token":
+		return true
+	}
+	return strings.HasPrefix(k, "grpc-") || strings.HasPrefix(k, "x-b3-")
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAddress(t *testing.T) {
+	if got, want := Address("foo", "bar"), "foo.bar.svc.cluster.local:80"; got != want {
+		t.Errorf("Address() = %s, wanted %s", got, want)
+	}
+	if got, want := Address("foo", ""), "foo.default.svc.cluster.local:80"; got != want {
+		t.Errorf("Address() = %s, wanted %s", got, want)
+	}
+}
+
+func TestForward(t *testing.T) {
+	in := metadata.Pairs(
+		"x-request-id", "abc",
+		"authorization", "Bearer secret",
+		"cookie", "session=secret",
+		"x-api-key", "key",
+		"grpc-timeout", "1S",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"korpc-resume-token", "42",
+	)
+
+	tests := []struct {
+		name string
+		keys []string
+		out  metadata.MD
+		want metadata.MD
+	}{{
+		name: "defaults",
+		keys: forwarded,
+		want: metadata.Pairs("x-request-id", "abc"),
+	}, {
+		name: "explicit",
+		keys: []string{"Authorization", "x-missing"},
+		want: metadata.Pairs("authorization", "Bearer secret"),
+	}, {
+		name: "hop headers",
+		keys: []string{"grpc-timeout", "traceparent", "korpc-resume-token"},
+		want: metadata.MD{},
+	}, {
+		name: "outgoing wins",
+		keys: []string{"x-api-key"},
+		out:  metadata.Pairs("x-api-key", "mine"),
+		want: metadata.Pairs("x-api-key", "mine"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), in)
+			if test.out != nil {
+				ctx = metadata.NewOutgoingContext(ctx, test.out)
+			}
+			got, _ := metadata.FromOutgoingContext(forward(ctx, test.keys))
+			if len(got) != len(test.want) {
+				t.Fatalf("forward() = %v, wanted %v", got, test.want)
+			}
+			for k, v := range test.want {
+				if g := got.Get(k); len(g) != 1 || g[0] != v[0] {
+					t.Errorf("forward()[%s] = %v, wanted %v", k, g, v)
+				}
+			}
+		})
+	}
+}
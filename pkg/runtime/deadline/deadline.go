@@ -0,0 +1,93 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deadline enforces a method's timeout_seconds as a deadline on the
+// context passed to Impl, so that handlers stop working on requests that
+// the queue-proxy has already given up on.
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mattmoor/korpc/pkg/runtime/metrics"
+)
+
+// UnaryServerInterceptor bounds each request by the given timeout, or by the
+// client's deadline if that is sooner.  A timeout of zero leaves only the
+// client's deadline.  Requests that outlive their deadline fail with
+// DeadlineExceeded, whatever the handler returned.
+func UnaryServerInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if err := check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor, bounding the entire stream.
+func StreamServerInterceptor(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withTimeout(ss.Context(), timeout)
+		defer cancel()
+
+		err := handler(srv, &deadlineStream{ServerStream: ss, ctx: ctx})
+		if err := check(ctx, info.FullMethod); err != nil {
+			return err
+		}
+		return err
+	}
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	// This keeps the client's deadline when it is sooner.
+	return context.WithTimeout(ctx, timeout)
+}
+
+// check returns DeadlineExceeded if the handler returned after the deadline,
+// recording how long it overran.
+func check(ctx context.Context, fullMethod string) error {
+	if ctx.Err() != context.DeadlineExceeded {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		metrics.RecordOverrun(ctx, fullMethod, time.Since(deadline))
+	}
+	return status.Errorf(codes.DeadlineExceeded, "%s exceeded its deadline", fullMethod)
+}
+
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+var _ grpc.ServerStream = (*deadlineStream)(nil)
+
+// Context implements grpc.ServerStream
+func (ds *deadlineStream) Context() context.Context {
+	return ds.ctx
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errors constructs errors that reach clients as gRPC statuses with
+// rich error details, and translates the errors returned by Impl into such
+// statuses.
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// New returns an error with the given code and message, carrying the given
+// errdetails messages.
+func New(c codes.Code, msg string, details ...proto.Message) error {
+	st := status.New(c, msg)
+	if len(details) == 0 {
+		return st.Err()
+	}
+	if detailed, err := st.WithDetails(details...); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// NotFound returns a NotFound error for the named resource.
+func NotFound(resourceType, name, msg string) error {
+	return New(codes.NotFound, msg, &errdetails.ResourceInfo{
+		ResourceType: resourceType,
+		ResourceName: name,
+		Description:  msg,
+	})
+}
+
+// AlreadyExists returns an AlreadyExists error for the named resource.
+func AlreadyExists(resourceType, name, msg string) error {
+	return New(codes.AlreadyExists, msg, &errdetails.ResourceInfo{
+		ResourceType: resourceType,
+		ResourceName: name,
+		Description:  msg,
+	})
+}
+
+// InvalidArgument returns an InvalidArgument error for the given request
+// field.
+func InvalidArgument(field, msg string) error {
+	return New(codes.InvalidArgument, msg, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: msg,
+		}},
+	})
+}
+
+// FailedPrecondition returns a FailedPrecondition error.
+func FailedPrecondition(msg string) error {
+	return New(codes.FailedPrecondition, msg)
+}
+
+// PermissionDenied returns a PermissionDenied error, where reason and domain
+// identify the cause for clients as in errdetails.ErrorInfo.
+func PermissionDenied(reason, domain, msg string) error {
+	return New(codes.PermissionDenied, msg, &errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: domain,
+	})
+}
+
+// Unavailable returns an Unavailable error, which tells clients to retry
+// after the given delay.
+func Unavailable(retryDelay time.Duration, msg string) error {
+	return New(codes.Unavailable, msg, &errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(retryDelay),
+	})
+}
+
+// ResourceExhausted returns a ResourceExhausted error for the quota held by
+// subject, which tells clients to retry after the given delay.
+func ResourceExhausted(retryDelay time.Duration, subject, msg string) error {
+	return New(codes.ResourceExhausted, msg, &errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(retryDelay),
+	}, &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     subject,
+			Description: msg,
+		}},
+	})
+}
+
+// Internal returns an Internal error.
+func Internal(msg string) error {
+	return New(codes.Internal, msg)
+}
+
+// WithErrorInfo returns err as a status carrying an additional
+// errdetails.ErrorInfo, which identifies the cause of the error for clients.
+func WithErrorInfo(err error, reason, domain string, metadata map[string]string) error {
+	st := Status(err)
+	if st == nil {
+		return nil
+	}
+	if detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   domain,
+		Metadata: metadata,
+	}); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// Status translates err into a gRPC status.  Statuses that have been wrapped
+// (e.g. with fmt.Errorf and %w) keep their code and details, context
+// cancellation and deadlines map to Canceled and DeadlineExceeded, and
+// anything else is Unknown.
+func Status(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	type grpcStatus interface {
+		GRPCStatus() *status.Status
+	}
+	if se, ok := err.(grpcStatus); ok {
+		return se.GRPCStatus()
+	}
+
+	var se grpcStatus
+	switch {
+	case stderrors.As(err, &se):
+		// Keep the context added by wrapping in the message.
+		p := se.GRPCStatus().Proto()
+		p.Message = err.Error()
+		return status.FromProto(p)
+	case stderrors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.New(codes.Unknown, err.Error())
+	}
+}
+
+// UnaryServerInterceptor translates the errors returned by the handler into
+// statuses.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, Status(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor translates the errors returned by the handler into
+// statuses.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return Status(err).Err()
+		}
+		return nil
+	}
+}
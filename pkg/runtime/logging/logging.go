@@ -0,0 +1,189 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging provides the structured, request-scoped logger that korpc
+// entrypoints place into the context passed to Impl.
+package logging
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Config holds the logging settings with which an entrypoint is generated.
+type Config struct {
+	// Service is the fully-qualified name of the GRPC service.
+	Service string
+
+	// Method is the name of the RPC method.
+	Method string
+
+	// Level is the minimum level to log, e.g. "debug" or "info".
+	Level string
+}
+
+type loggerKey struct{}
+
+// fallback is returned by FromContext outside of a korpc entrypoint, e.g.
+// when unit testing Impl.
+var fallback, _ = New(Config{Level: "info"})
+
+// New creates a logger that writes JSON lines to stderr using the field names
+// that Cloud Logging and Loki expect, tagged with the service, method and the
+// Knative revision (from K_REVISION).
+func New(cfg Config) (*zap.SugaredLogger, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, err
+	}
+
+	zc := zap.NewProductionConfig()
+	zc.Level = zap.NewAtomicLevelAt(level)
+	zc.EncoderConfig.MessageKey = "message"
+	zc.EncoderConfig.LevelKey = "severity"
+	zc.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	zc.EncoderConfig.TimeKey = "timestamp"
+	zc.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+
+	logger, err := zc.Build()
+	if err != nil {
+		return nil, err
+	}
+	fields := []interface{}{}
+	if cfg.Service != "" {
+		fields = append(fields, "service", cfg.Service)
+	}
+	if cfg.Method != "" {
+		fields = append(fields, "method", cfg.Method)
+	}
+	if rev := os.Getenv("K_REVISION"); rev != "" {
+		fields = append(fields, "revision", rev)
+	}
+	return logger.Sugar().With(fields...), nil
+}
+
+// FromContext returns the logger for the request being handled.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// WithLogger returns a copy of ctx carrying the given logger.
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// UnaryServerInterceptor places a request-scoped logger into the context, and
+// optionally logs a line per call.
+func UnaryServerInterceptor(base *zap.SugaredLogger, accessLog bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		logger := forRequest(ctx, base)
+
+		resp, err := handler(WithLogger(ctx, logger), req)
+		if accessLog {
+			access(logger, start, err)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor places a request-scoped logger into the stream's
+// context, and optionally logs a line per call.
+func StreamServerInterceptor(base *zap.SugaredLogger, accessLog bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		logger := forRequest(ss.Context(), base)
+
+		err := handler(srv, &loggingStream{
+			ServerStream: ss,
+			ctx:          WithLogger(ss.Context(), logger),
+		})
+		if accessLog {
+			access(logger, start, err)
+		}
+		return err
+	}
+}
+
+// forRequest tags the base logger with the trace and caller of the request.
+func forRequest(ctx context.Context, base *zap.SugaredLogger) *zap.SugaredLogger {
+	fields := []interface{}{}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, "peer", p.Addr.String())
+	}
+	if caller := callerIdentity(ctx); caller != "" {
+		fields = append(fields, "caller", caller)
+	}
+	return base.With(fields...)
+}
+
+// callerIdentity returns the SPIFFE identity of the calling workload, which
+// the Istio sidecar forwards in the x-forwarded-client-cert header when mTLS
+// is enabled.
+func callerIdentity(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, xfcc := range md.Get("x-forwarded-client-cert") {
+		// The last element was added by the hop closest to us.
+		elements := strings.Split(xfcc, ",")
+		for _, pair := range strings.Split(elements[len(elements)-1], ";") {
+			if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 && strings.EqualFold(kv[0], "URI") {
+				return strings.Trim(kv[1], `"`)
+			}
+		}
+	}
+	return ""
+}
+
+func access(logger *zap.SugaredLogger, start time.Time, err error) {
+	fields := []interface{}{
+		"code", status.Code(err).String(),
+		"latency", time.Since(start).String(),
+	}
+	if err != nil {
+		logger.Warnw("access", append(fields, "error", err.Error())...)
+		return
+	}
+	logger.Infow("access", fields...)
+}
+
+type loggingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+var _ grpc.ServerStream = (*loggingStream)(nil)
+
+// Context implements grpc.ServerStream
+func (ls *loggingStream) Context() context.Context {
+	return ls.ctx
+}
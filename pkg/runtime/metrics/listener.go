@@ -0,0 +1,147 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"contrib.go.opencensus.io/exporter/prometheus"
+)
+
+// sniffTimeout bounds how long a new connection may take to send the bytes
+// by which we tell gRPC from metrics scrapes.
+const sniffTimeout = 10 * time.Second
+
+// preface starts every HTTP/2 connection, and so every h2c gRPC connection.
+var preface = []byte("PRI * HTTP/2.0")
+
+// Split serves the metrics on path over the HTTP/1 connections accepted by
+// lis, and returns a listener of the rest (i.e. the h2c connections for the
+// gRPC server).  Knative routes a single port to the container, so the
+// metrics have to share it with the method.
+func Split(lis net.Listener, path string) (net.Listener, error) {
+	pe, err := prometheus.NewExporter(prometheus.Options{})
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, pe)
+
+	grpcLis := newChanListener(lis.Addr())
+	httpLis := newChanListener(lis.Addr())
+	go func() {
+		defer grpcLis.Close()
+		defer httpLis.Close()
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go route(conn, grpcLis, httpLis)
+		}
+	}()
+	go func() {
+		if err := http.Serve(httpLis, mux); err != nil && err != errClosed {
+			log.Printf("Error serving metrics: %v", err)
+		}
+	}()
+	return grpcLis, nil
+}
+
+// route hands the connection to the listener for its protocol.
+func route(conn net.Conn, grpcLis, httpLis *chanListener) {
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	br := bufio.NewReaderSize(conn, len(preface))
+	peek, err := br.Peek(len(preface))
+	conn.SetReadDeadline(time.Time{})
+	if err != nil && len(peek) == 0 {
+		conn.Close()
+		return
+	}
+
+	sc := &sniffedConn{Conn: conn, r: br}
+	if bytes.Equal(peek, preface) {
+		grpcLis.deliver(sc)
+	} else {
+		httpLis.deliver(sc)
+	}
+}
+
+// sniffedConn replays the bytes that were read to route the connection.
+type sniffedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read implements net.Conn
+func (sc *sniffedConn) Read(b []byte) (int, error) {
+	return sc.r.Read(b)
+}
+
+var errClosed = errors.New("listener closed")
+
+// chanListener is a net.Listener of the connections delivered to it.
+type chanListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+var _ net.Listener = (*chanListener)(nil)
+
+func newChanListener(addr net.Addr) *chanListener {
+	return &chanListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (cl *chanListener) deliver(conn net.Conn) {
+	select {
+	case cl.conns <- conn:
+	case <-cl.done:
+		conn.Close()
+	}
+}
+
+// Accept implements net.Listener
+func (cl *chanListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-cl.conns:
+		return conn, nil
+	case <-cl.done:
+		return nil, errClosed
+	}
+}
+
+// Close implements net.Listener
+func (cl *chanListener) Close() error {
+	cl.once.Do(func() { close(cl.done) })
+	return nil
+}
+
+// Addr implements net.Listener
+func (cl *chanListener) Addr() net.Addr {
+	return cl.addr
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestSplit(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen() = %v", err)
+	}
+	grpcLis, err := Split(lis, "/metrics")
+	if err != nil {
+		t.Fatalf("Split() = %v", err)
+	}
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(grpcLis)
+	defer s.Stop()
+
+	// gRPC is served on the port...
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() = %v", err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+	if got, want := resp.Status, healthpb.HealthCheckResponse_SERVING; got != want {
+		t.Errorf("Check() = %v, wanted %v", got, want)
+	}
+
+	// ...alongside the metrics.
+	if err := Register(); err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+	defer track("/grpc.health.v1.Health/Check")()
+	hr, err := http.Get("http://" + lis.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("Get() = %v", err)
+	}
+	defer hr.Body.Close()
+	body, err := ioutil.ReadAll(hr.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() = %v", err)
+	}
+	if hr.StatusCode != http.StatusOK {
+		t.Fatalf("Get() = %d: %s", hr.StatusCode, body)
+	}
+	if !strings.Contains(string(body), "korpc_server_in_flight_requests") {
+		t.Errorf("Get() = %s, wanted the in-flight gauge", body)
+	}
+}
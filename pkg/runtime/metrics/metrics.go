@@ -0,0 +1,213 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics contains the runtime support for the Prometheus metrics
+// that korpc entrypoints serve when a method sets the metrics option.
+package metrics
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"go.opencensus.io/metric"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/metric/metricproducer"
+	"go.opencensus.io/plugin/ocgrpc"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc"
+)
+
+var (
+	// MessagesReceived counts the messages received on server streams.
+	MessagesReceived = stats.Int64("korpc/server/stream_messages_received",
+		"Number of messages received on server streams.", stats.UnitDimensionless)
+
+	// MessagesSent counts the messages sent on server streams.
+	MessagesSent = stats.Int64("korpc/server/stream_messages_sent",
+		"Number of messages sent on server streams.", stats.UnitDimensionless)
+
+	// MessagesReceivedView is the per-method count of MessagesReceived.
+	MessagesReceivedView = &view.View{
+		Name:        "korpc/server/stream_messages_received",
+		Description: "Count of messages received on server streams, by method.",
+		TagKeys:     []tag.Key{ocgrpc.KeyServerMethod},
+		Measure:     MessagesReceived,
+		Aggregation: view.Count(),
+	}
+
+	// MessagesSentView is the per-method count of MessagesSent.
+	MessagesSentView = &view.View{
+		Name:        "korpc/server/stream_messages_sent",
+		Description: "Count of messages sent on server streams, by method.",
+		TagKeys:     []tag.Key{ocgrpc.KeyServerMethod},
+		Measure:     MessagesSent,
+		Aggregation: view.Count(),
+	}
+
+	// HandlerOverrun measures how long handlers kept running past their
+	// deadline, in milliseconds.
+	HandlerOverrun = stats.Float64("korpc/server/handler_overrun",
+		"Time handlers kept running past their deadline.", stats.UnitMilliseconds)
+
+	// HandlerOverrunView is the per-method distribution of HandlerOverrun.
+	HandlerOverrunView = &view.View{
+		Name:        "korpc/server/handler_overrun",
+		Description: "Distribution of the time handlers kept running past their deadline, by method.",
+		TagKeys:     []tag.Key{ocgrpc.KeyServerMethod},
+		Measure:     HandlerOverrun,
+		Aggregation: view.Distribution(0, 10, 100, 1000, 10000, 60000),
+	}
+
+	// KeyCacheResult tags cache lookups as a "hit" or "miss".
+	KeyCacheResult = tag.MustNewKey("korpc_cache_result")
+
+	// CacheLookups counts lookups in the response cache.
+	CacheLookups = stats.Int64("korpc/server/cache_lookups",
+		"Number of lookups in the response cache.", stats.UnitDimensionless)
+
+	// CacheLookupsView is the per-method count of CacheLookups, by result.
+	CacheLookupsView = &view.View{
+		Name:        "korpc/server/cache_lookups",
+		Description: "Count of lookups in the response cache, by method and result.",
+		TagKeys:     []tag.Key{ocgrpc.KeyServerMethod, KeyCacheResult},
+		Measure:     CacheLookups,
+		Aggregation: view.Count(),
+	}
+
+	// DefaultViews are the views that korpc entrypoints register in addition
+	// to ocgrpc.DefaultServerViews.
+	DefaultViews = []*view.View{
+		MessagesReceivedView,
+		MessagesSentView,
+		HandlerOverrunView,
+		CacheLookupsView,
+	}
+
+	registry = metric.NewRegistry()
+	inFlight *metric.Int64Gauge
+)
+
+func init() {
+	var err error
+	inFlight, err = registry.AddInt64Gauge("korpc/server/in_flight_requests",
+		metric.WithDescription("Number of requests currently being handled, by method."),
+		metric.WithLabelKeys(ocgrpc.KeyServerMethod.Name()))
+	if err != nil {
+		log.Fatalf("Error creating in-flight gauge: %v", err)
+	}
+}
+
+// Register starts collecting the DefaultViews and the in-flight gauge.  Other
+// runtime packages record measurements here regardless, which are dropped
+// unless this has been called.
+func Register() error {
+	if err := view.Register(DefaultViews...); err != nil {
+		return err
+	}
+	metricproducer.GlobalManager().AddProducer(registry)
+	return nil
+}
+
+// UnaryServerInterceptor tracks the number of in-flight unary requests.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		defer track(info.FullMethod)()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor tracks the number of in-flight streams, and counts
+// the messages received and sent on each.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		defer track(info.FullMethod)()
+
+		ctx, err := tag.New(ss.Context(),
+			tag.Upsert(ocgrpc.KeyServerMethod, methodName(info.FullMethod)))
+		if err != nil {
+			return err
+		}
+		return handler(srv, &countingStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RecordOverrun records that the handler for the method returned the given
+// duration after its deadline.
+func RecordOverrun(ctx context.Context, fullMethod string, overrun time.Duration) {
+	stats.RecordWithTags(ctx,
+		[]tag.Mutator{tag.Upsert(ocgrpc.KeyServerMethod, methodName(fullMethod))},
+		HandlerOverrun.M(float64(overrun)/float64(time.Millisecond)))
+}
+
+// RecordCacheLookup records a lookup in the method's response cache.
+func RecordCacheLookup(ctx context.Context, fullMethod string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	stats.RecordWithTags(ctx,
+		[]tag.Mutator{
+			tag.Upsert(ocgrpc.KeyServerMethod, methodName(fullMethod)),
+			tag.Upsert(KeyCacheResult, result),
+		},
+		CacheLookups.M(1))
+}
+
+// track increments the in-flight gauge for the method, and returns a function
+// to decrement it once the request completes.
+func track(fullMethod string) func() {
+	entry, err := inFlight.GetEntry(metricdata.NewLabelValue(methodName(fullMethod)))
+	if err != nil {
+		log.Printf("Error tracking in-flight request: %v", err)
+		return func() {}
+	}
+	entry.Add(1)
+	return func() {
+		entry.Add(-1)
+	}
+}
+
+// ocgrpc tags methods without the leading slash, so we match it.
+func methodName(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+var _ grpc.ServerStream = (*countingStream)(nil)
+
+// RecvMsg implements grpc.ServerStream
+func (cs *countingStream) RecvMsg(m interface{}) error {
+	err := cs.ServerStream.RecvMsg(m)
+	if err == nil {
+		stats.Record(cs.ctx, MessagesReceived.M(1))
+	}
+	return err
+}
+
+// SendMsg implements grpc.ServerStream
+func (cs *countingStream) SendMsg(m interface{}) error {
+	err := cs.ServerStream.SendMsg(m)
+	if err == nil {
+		stats.Record(cs.ctx, MessagesSent.M(1))
+	}
+	return err
+}
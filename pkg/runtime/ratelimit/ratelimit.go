@@ -0,0 +1,185 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit sheds the requests to korpc entrypoints that exceed a
+// method's rate limits, so that callers fail fast instead of queueing.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mattmoor/korpc/pkg/runtime/auth"
+	"github.com/mattmoor/korpc/pkg/runtime/errors"
+	"github.com/mattmoor/korpc/pkg/runtime/internal/health"
+)
+
+const (
+	// How long callers shed for being over max in-flight are told to wait.
+	inFlightRetryDelay = time.Second
+
+	// How often idle quotas are forgotten.
+	sweepInterval = time.Minute
+)
+
+// Config holds the rate limit settings with which an entrypoint is generated.
+type Config struct {
+	// RequestsPerSecond is the sustained rate allowed for each key, or
+	// unlimited when zero.
+	RequestsPerSecond float64
+
+	// Burst is how many requests for each key may arrive at once.
+	Burst int
+
+	// KeyHeader is the metadata header that keys the quota.
+	KeyHeader string
+
+	// KeyClaim is the verified token claim that keys the quota, which takes
+	// precedence over KeyHeader.
+	KeyClaim string
+
+	// MaxInFlight is the maximum number of requests handled at once, or
+	// unlimited when zero.
+	MaxInFlight int
+}
+
+// Limiter enforces a Config.
+type Limiter struct {
+	cfg      Config
+	inFlight int64
+
+	m         sync.Mutex
+	quotas    map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// New returns a Limiter for the given Config.
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		cfg:       cfg,
+		quotas:    make(map[string]*rate.Limiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// Acquire admits a request, returning a function to call once it completes,
+// or a ResourceExhausted error carrying RetryInfo if it must be shed.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	// Check concurrency first, so that shed requests don't use up quota.
+	release := func() {}
+	if l.cfg.MaxInFlight > 0 {
+		if atomic.AddInt64(&l.inFlight, 1) > int64(l.cfg.MaxInFlight) {
+			atomic.AddInt64(&l.inFlight, -1)
+			return nil, errors.ResourceExhausted(inFlightRetryDelay, "",
+				fmt.Sprintf("more than %d requests in flight", l.cfg.MaxInFlight))
+		}
+		release = func() {
+			atomic.AddInt64(&l.inFlight, -1)
+		}
+	}
+
+	if l.cfg.RequestsPerSecond > 0 {
+		key := l.key(ctx)
+		now := time.Now()
+		r := l.quota(key, now).ReserveN(now, 1)
+		if !r.OK() {
+			release()
+			return nil, errors.ResourceExhausted(0, key, "request exceeds the rate limit")
+		}
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			release()
+			return nil, errors.ResourceExhausted(delay, key,
+				fmt.Sprintf("rate limit of %g requests per second exceeded", l.cfg.RequestsPerSecond))
+		}
+	}
+	return release, nil
+}
+
+// key returns the quota key for the request.
+func (l *Limiter) key(ctx context.Context) string {
+	if l.cfg.KeyClaim != "" {
+		if v, ok := auth.ClaimsFromContext(ctx)[l.cfg.KeyClaim]; ok {
+			return fmt.Sprint(v)
+		}
+	}
+	if l.cfg.KeyHeader != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(l.cfg.KeyHeader); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// quota returns the rate limiter for the key, periodically forgetting those
+// that have refilled completely, since they are indistinguishable from new.
+func (l *Limiter) quota(key string, now time.Time) *rate.Limiter {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		for k, q := range l.quotas {
+			if q.TokensAt(now) >= float64(l.cfg.Burst) {
+				delete(l.quotas, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	q, ok := l.quotas[key]
+	if !ok {
+		q = rate.NewLimiter(rate.Limit(l.cfg.RequestsPerSecond), l.cfg.Burst)
+		l.quotas[key] = q
+	}
+	return q
+}
+
+// UnaryServerInterceptor sheds requests that exceed the limits.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if health.IsCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor sheds streams that exceed the limits.  Each stream
+// counts as a single request.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if health.IsCheck(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		release, err := l.Acquire(ss.Context())
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mattmoor/korpc/pkg/runtime/auth"
+)
+
+func TestAcquireRate(t *testing.T) {
+	l := New(Config{
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+		KeyHeader:         "x-api-key",
+	})
+	alice := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "alice"))
+	bob := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "bob"))
+
+	if _, err := l.Acquire(alice); err != nil {
+		t.Fatalf("Acquire(alice) = %v", err)
+	}
+	if _, err := l.Acquire(alice); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire(alice) = %v, wanted %v", err, codes.ResourceExhausted)
+	}
+	// Each key has its own quota.
+	if _, err := l.Acquire(bob); err != nil {
+		t.Errorf("Acquire(bob) = %v", err)
+	}
+}
+
+func TestAcquireKeyClaim(t *testing.T) {
+	l := New(Config{
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+		KeyClaim:          "sub",
+	})
+	alice := auth.WithClaims(context.Background(), auth.Claims{"sub": "alice"})
+	bob := auth.WithClaims(context.Background(), auth.Claims{"sub": "bob"})
+
+	if _, err := l.Acquire(alice); err != nil {
+		t.Fatalf("Acquire(alice) = %v", err)
+	}
+	if _, err := l.Acquire(alice); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire(alice) = %v, wanted %v", err, codes.ResourceExhausted)
+	}
+	if _, err := l.Acquire(bob); err != nil {
+		t.Errorf("Acquire(bob) = %v", err)
+	}
+}
+
+func TestAcquireInFlight(t *testing.T) {
+	l := New(Config{
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+		MaxInFlight:       1,
+	})
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+	// Shedding this for being over max_in_flight must not use up the
+	// second request of the burst.
+	if _, err := l.Acquire(ctx); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire() = %v, wanted %v", err, codes.ResourceExhausted)
+	}
+	release()
+
+	release, err = l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+	release()
+
+	// Now the burst is spent, and requests shed by the rate limit don't hold
+	// onto their in-flight slot.
+	if _, err := l.Acquire(ctx); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Acquire() = %v, wanted %v", err, codes.ResourceExhausted)
+	}
+	if got := l.inFlight; got != 0 {
+		t.Errorf("inFlight = %d, wanted 0", got)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package streaming keeps the streams of korpc entrypoints alive through
+// proxies, and ends them with a resumable status before the revision's
+// timeout or shutdown, so that clients can resume them where they left off.
+package streaming
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// The metadata key on which clients send the token to resume from.
+	resumeTokenKey = "korpc-resume-token"
+
+	// The errdetails.ErrorInfo that identifies a resumable status.
+	resumableReason = "STREAM_RESUMABLE"
+	resumableDomain = "korpc"
+
+	// How many times in a row Resume reopens a stream that makes no progress.
+	maxResumeAttempts = 10
+)
+
+// The bounds of the backoff between reopening a stream.  These are variables
+// for testing.
+var (
+	minResumeBackoff = 100 * time.Millisecond
+	maxResumeBackoff = 10 * time.Second
+)
+
+// Config holds the stream settings with which an entrypoint is generated.
+type Config struct {
+	// Heartbeat is how often to send a response on an otherwise idle stream,
+	// or never when zero.
+	Heartbeat time.Duration
+
+	// MaxDuration is how long a stream may run before it ends with a
+	// resumable status, or unbounded when zero.
+	MaxDuration time.Duration
+
+	// NewResponse returns the empty response message sent as a heartbeat.
+	NewResponse func() interface{}
+}
+
+// Manager enforces a Config on the streams of a method.
+type Manager struct {
+	cfg Config
+
+	m       sync.Mutex
+	active  map[*managedStream]struct{}
+	drained bool
+}
+
+// New returns a Manager for the given Config.
+func New(cfg Config) *Manager {
+	return &Manager{
+		cfg:    cfg,
+		active: make(map[*managedStream]struct{}),
+	}
+}
+
+// Drain ends all active streams with a resumable status, so that the server
+// can stop gracefully.  Streams started afterwards end immediately.
+func (m *Manager) Drain() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.drained = true
+	for ms := range m.active {
+		ms.end()
+	}
+}
+
+func (m *Manager) track(ms *managedStream) func() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.drained {
+		ms.end()
+	}
+	m.active[ms] = struct{}{}
+	return func() {
+		m.m.Lock()
+		defer m.m.Unlock()
+		delete(m.active, ms)
+	}
+}
+
+// StreamServerInterceptor sends heartbeats on idle streams, and ends them
+// with a resumable status at MaxDuration or when drained.
+func (m *Manager) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		parent := ss.Context()
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if m.cfg.MaxDuration > 0 {
+			ctx, cancel = context.WithTimeout(parent, m.cfg.MaxDuration)
+		} else {
+			ctx, cancel = context.WithCancel(parent)
+		}
+		defer cancel()
+
+		ms := &managedStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ctx, tokenKey{}, &token{}),
+			cancel:       cancel,
+			lastSend:     time.Now(),
+		}
+		defer m.track(ms)()
+
+		if m.cfg.Heartbeat > 0 && info.IsServerStream {
+			done := make(chan struct{})
+			defer close(done)
+			go ms.heartbeat(m.cfg.Heartbeat, m.cfg.NewResponse, done)
+		}
+
+		err := handler(srv, ms)
+		if parent.Err() == nil && ctx.Err() != nil {
+			// We ended the stream, rather than the client or an upstream deadline.
+			return resumable(ms.ctx)
+		}
+		return err
+	}
+}
+
+type managedStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// gRPC streams do not support concurrent sends, so the heartbeat
+	// serializes with the handler.
+	m        sync.Mutex
+	lastSend time.Time
+}
+
+var _ grpc.ServerStream = (*managedStream)(nil)
+
+// Context implements grpc.ServerStream
+func (ms *managedStream) Context() context.Context {
+	return ms.ctx
+}
+
+// SendMsg implements grpc.ServerStream
+func (ms *managedStream) SendMsg(m interface{}) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	ms.lastSend = time.Now()
+	return ms.ServerStream.SendMsg(m)
+}
+
+func (ms *managedStream) end() {
+	ms.cancel()
+}
+
+// heartbeat sends a response whenever nothing has been sent for interval.
+func (ms *managedStream) heartbeat(interval time.Duration, newResponse func() interface{}, done <-chan struct{}) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ms.ctx.Done():
+			return
+		case <-ticker.C:
+			ms.m.Lock()
+			if time.Since(ms.lastSend) >= interval {
+				ms.lastSend = time.Now()
+				// Failures surface to the handler on its next send.
+				ms.ServerStream.SendMsg(newResponse())
+			}
+			ms.m.Unlock()
+		}
+	}
+}
+
+type tokenKey struct{}
+
+type token struct {
+	m     sync.Mutex
+	value string
+}
+
+// SetResumeToken records the position in the stream served by ctx from
+// which a client could resume it, typically alongside each response sent.
+func SetResumeToken(ctx context.Context, value string) {
+	if t, ok := ctx.Value(tokenKey{}).(*token); ok {
+		t.m.Lock()
+		defer t.m.Unlock()
+		t.value = value
+	}
+}
+
+// ResumeTokenFromContext returns the token from which the client asked to
+// resume the stream served by ctx, or "" for a new stream.
+func ResumeTokenFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(resumeTokenKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// resumable returns the Unavailable status with which streams end when a
+// client should resume them, carrying the last recorded resume token.
+func resumable(ctx context.Context) error {
+	var value string
+	if t, ok := ctx.Value(tokenKey{}).(*token); ok {
+		t.m.Lock()
+		value = t.value
+		t.m.Unlock()
+	}
+	st := status.New(codes.Unavailable, "stream ended, resume from the token to continue")
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   resumableReason,
+		Domain:   resumableDomain,
+		Metadata: map[string]string{"resume_token": value},
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// ResumeToken returns the token from which to resume a stream that ended
+// with err, and whether it may be resumed.
+func ResumeToken(err error) (string, bool) {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok &&
+			info.Reason == resumableReason && info.Domain == resumableDomain {
+			return info.Metadata["resume_token"], true
+		}
+	}
+	return "", false
+}
+
+// WithResumeToken returns a context for opening a stream that resumes from
+// the given token.
+func WithResumeToken(ctx context.Context, value string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, resumeTokenKey, value)
+}
+
+// Resume calls open to run a stream to completion, reopening it from its
+// resume token each time it ends with a resumable status.  It returns the
+// error with which the stream finally ends, or ctx's error if ctx is done
+// first.  Streams are reopened with jittered exponential backoff, which
+// resets whenever the stream makes progress (its resume token changes), and
+// Resume gives up after maxResumeAttempts reopenings in a row without
+// progress.
+func Resume(ctx context.Context, open func(ctx context.Context) error) error {
+	err := open(ctx)
+	last, attempts := "", 0
+	for {
+		value, ok := ResumeToken(err)
+		if !ok {
+			return err
+		}
+		if value != last {
+			last, attempts = value, 0
+		}
+		if attempts == maxResumeAttempts {
+			return err
+		}
+
+		t := time.NewTimer(backoff(attempts))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		attempts++
+		err = open(WithResumeToken(ctx, value))
+	}
+}
+
+// backoff returns how long to wait before the given attempt at reopening a
+// stream, with full jitter so that the clients of a revision that is
+// shutting down don't all return at once.
+func backoff(attempt int) time.Duration {
+	d := maxResumeBackoff
+	if attempt < 16 {
+		if exp := minResumeBackoff << uint(attempt); exp < d {
+			d = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(d))) + 1
+}
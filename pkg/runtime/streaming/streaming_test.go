@@ -0,0 +1,117 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streaming
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// resumableAt returns the status with which a stream ends that should be
+// resumed from the given token.
+func resumableAt(value string) error {
+	return resumable(context.WithValue(context.Background(), tokenKey{}, &token{value: value}))
+}
+
+// sentToken returns the resume token that a stream opened with ctx sends.
+func sentToken(ctx context.Context) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get(resumeTokenKey); len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+func fastBackoff(t *testing.T) {
+	oldMin, oldMax := minResumeBackoff, maxResumeBackoff
+	minResumeBackoff, maxResumeBackoff = time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		minResumeBackoff, maxResumeBackoff = oldMin, oldMax
+	})
+}
+
+func TestResume(t *testing.T) {
+	fastBackoff(t)
+
+	done := errors.New("done")
+	var sent []string
+	err := Resume(context.Background(), func(ctx context.Context) error {
+		sent = append(sent, sentToken(ctx))
+		if len(sent) == 20 {
+			return done
+		}
+		return resumableAt(string(rune('a' + len(sent))))
+	})
+	if err != done {
+		t.Errorf("Resume() = %v, wanted %v", err, done)
+	}
+	// Streams that make progress are resumed for as long as they go on.
+	if got, want := len(sent), 20; got != want {
+		t.Fatalf("opened %d times, wanted %d", got, want)
+	}
+	for i, got := range sent {
+		want := ""
+		if i > 0 {
+			want = string(rune('a' + i))
+		}
+		if got != want {
+			t.Errorf("open #%d had token %q, wanted %q", i, got, want)
+		}
+	}
+}
+
+func TestResumeWithoutProgress(t *testing.T) {
+	fastBackoff(t)
+
+	opens := 0
+	err := Resume(context.Background(), func(ctx context.Context) error {
+		opens++
+		return resumableAt("stuck")
+	})
+	if _, ok := ResumeToken(err); !ok {
+		t.Errorf("Resume() = %v, wanted the resumable status", err)
+	}
+	if got, want := opens, maxResumeAttempts+1; got != want {
+		t.Errorf("opened %d times, wanted %d", got, want)
+	}
+}
+
+func TestResumeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opens := 0
+	err := Resume(ctx, func(ctx context.Context) error {
+		opens++
+		cancel()
+		return resumableAt("a")
+	})
+	if err != context.Canceled {
+		t.Errorf("Resume() = %v, wanted %v", err, context.Canceled)
+	}
+	if opens != 1 {
+		t.Errorf("opened %d times, wanted 1", opens)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 100; attempt++ {
+		if d := backoff(attempt); d <= 0 || d > maxResumeBackoff {
+			t.Errorf("backoff(%d) = %v, wanted (0, %v]", attempt, d, maxResumeBackoff)
+		}
+	}
+}
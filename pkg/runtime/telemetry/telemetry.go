@@ -0,0 +1,152 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry sets up OpenTelemetry tracing and metrics for korpc
+// entrypoints, exporting them over OTLP.
+package telemetry
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/stats"
+)
+
+const (
+	// The standard environment variables that take precedence over Config.
+	endpointEnv    = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	sampleRatioEnv = "OTEL_TRACES_SAMPLER_ARG"
+)
+
+// Config holds the telemetry settings with which an entrypoint is generated.
+type Config struct {
+	// ServiceName is reported as the service.name resource attribute,
+	// unless OTEL_SERVICE_NAME is set.
+	ServiceName string
+
+	// Endpoint is the host:port of the OTLP gRPC collector.  When neither
+	// this nor OTEL_EXPORTER_OTLP_ENDPOINT is set nothing is exported, but
+	// incoming trace context is still propagated.
+	Endpoint string
+
+	// Insecure connects to Endpoint without TLS.
+	Insecure bool
+
+	// SampleRatio is the fraction of new traces to sample.  Traces started
+	// by the caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer and meter providers and the W3C trace
+// context and B3 propagators (the latter being what Istio forwards).  The
+// returned function flushes and stops the providers.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)),
+	))
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", cfg.ServiceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cfg.SampleRatio
+	if v := os.Getenv(sampleRatioEnv); v != "" {
+		if ratio, err = strconv.ParseFloat(v, 64); err != nil {
+			log.Printf("Ignoring malformed %s=%q: %v", sampleRatioEnv, v, err)
+			ratio = cfg.SampleRatio
+		}
+	}
+
+	topts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}
+	mopts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+	}
+
+	if os.Getenv(endpointEnv) != "" || cfg.Endpoint != "" {
+		te, err := otlptracegrpc.New(ctx, traceOptions(cfg)...)
+		if err != nil {
+			return nil, err
+		}
+		topts = append(topts, sdktrace.WithBatcher(te))
+
+		me, err := otlpmetricgrpc.New(ctx, metricOptions(cfg)...)
+		if err != nil {
+			return nil, err
+		}
+		mopts = append(mopts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(me)))
+	}
+
+	tp := sdktrace.NewTracerProvider(topts...)
+	mp := sdkmetric.NewMeterProvider(mopts...)
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+
+	return func(ctx context.Context) error {
+		if err := tp.Shutdown(ctx); err != nil {
+			return err
+		}
+		return mp.Shutdown(ctx)
+	}, nil
+}
+
+// ServerHandler returns a grpc stats handler that continues the caller's
+// trace, and places the server span in the context handed to impl.Impl.
+func ServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler()
+}
+
+// The exporters read the OTEL_* environment themselves, and explicit options
+// would override it, so we only pass the Config when the environment is unset.
+func traceOptions(cfg Config) []otlptracegrpc.Option {
+	if os.Getenv(endpointEnv) != "" {
+		return nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return opts
+}
+
+func metricOptions(cfg Config) []otlpmetricgrpc.Option {
+	if os.Getenv(endpointEnv) != "" {
+		return nil
+	}
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	return opts
+}
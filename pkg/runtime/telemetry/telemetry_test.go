@@ -0,0 +1,172 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	collectormetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// receiver is an in-process OTLP collector that records the spans exported
+// to it.
+type receiver struct {
+	collectortracepb.UnimplementedTraceServiceServer
+
+	m     sync.Mutex
+	spans []*tracepb.ResourceSpans
+}
+
+func (r *receiver) Export(ctx context.Context, req *collectortracepb.ExportTraceServiceRequest) (*collectortracepb.ExportTraceServiceResponse, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.spans = append(r.spans, req.ResourceSpans...)
+	return &collectortracepb.ExportTraceServiceResponse{}, nil
+}
+
+// metricsReceiver discards the metrics, which are flushed on shutdown.
+type metricsReceiver struct {
+	collectormetricpb.UnimplementedMetricsServiceServer
+}
+
+func (metricsReceiver) Export(context.Context, *collectormetricpb.ExportMetricsServiceRequest) (*collectormetricpb.ExportMetricsServiceResponse, error) {
+	return &collectormetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+// serve starts a grpc server on a local port, returning its address.
+func serve(t *testing.T, s *grpc.Server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen() = %v", err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestSetup(t *testing.T) {
+	t.Setenv(endpointEnv, "")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+	t.Setenv(sampleRatioEnv, "")
+
+	r := &receiver{}
+	collector := grpc.NewServer()
+	collectortracepb.RegisterTraceServiceServer(collector, r)
+	collectormetricpb.RegisterMetricsServiceServer(collector, metricsReceiver{})
+	endpoint := serve(t, collector)
+
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, Config{
+		ServiceName: "sampleservice-unary",
+		Endpoint:    endpoint,
+		Insecure:    true,
+		// Nothing should be sampled on our own, so the span below is
+		// only exported because the caller sampled it.
+		SampleRatio: 0,
+	})
+	if err != nil {
+		t.Fatalf("Setup() = %v", err)
+	}
+
+	s := grpc.NewServer(grpc.StatsHandler(ServerHandler()))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	addr := serve(t, s)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() = %v", err)
+	}
+	defer conn.Close()
+
+	// Continue a sampled trace started by the caller, as the queue-proxy
+	// or another method would.
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	cctx := metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+traceID+"-"+spanID+"-01")
+	if _, err := healthpb.NewHealthClient(conn).Check(cctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+
+	sctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := shutdown(sctx); err != nil {
+		t.Fatalf("shutdown() = %v", err)
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	var found *tracepb.Span
+	for _, rs := range r.spans {
+		var name string
+		for _, kv := range rs.GetResource().GetAttributes() {
+			if kv.Key == "service.name" {
+				name = kv.Value.GetStringValue()
+			}
+		}
+		if got, want := name, "sampleservice-unary"; got != want {
+			t.Errorf("service.name = %q, wanted %q", got, want)
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				if span.Name == "grpc.health.v1.Health/Check" {
+					found = span
+				}
+			}
+		}
+	}
+	if found == nil {
+		t.Fatalf("Exported spans = %v, wanted grpc.health.v1.Health/Check", r.spans)
+	}
+
+	if got, want := hex.EncodeToString(found.TraceId), traceID; got != want {
+		t.Errorf("TraceId = %s, wanted %s", got, want)
+	}
+	if got, want := hex.EncodeToString(found.ParentSpanId), spanID; got != want {
+		t.Errorf("ParentSpanId = %s, wanted %s", got, want)
+	}
+	if got, want := found.Kind, tracepb.Span_SPAN_KIND_SERVER; got != want {
+		t.Errorf("Kind = %v, wanted %v", got, want)
+	}
+
+	attrs := make(map[string]string, len(found.Attributes))
+	for _, kv := range found.Attributes {
+		attrs[kv.Key] = kv.Value.GetStringValue()
+	}
+	for k, want := range map[string]string{
+		"rpc.system":  "grpc",
+		"rpc.service": "grpc.health.v1.Health",
+		"rpc.method":  "Check",
+	} {
+		if got := attrs[k]; got != want {
+			t.Errorf("%s = %q, wanted %q", k, got, want)
+		}
+	}
+}
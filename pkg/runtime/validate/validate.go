@@ -0,0 +1,136 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validate checks requests against the rules that protoc-gen-validate
+// generates for them before korpc entrypoints call Impl.
+package validate
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The methods that protoc-gen-validate generates on messages and errors.
+type (
+	validator interface {
+		Validate() error
+	}
+	allValidator interface {
+		ValidateAll() error
+	}
+	fieldError interface {
+		Field() string
+		Reason() string
+		Cause() error
+	}
+	multiError interface {
+		AllErrors() []error
+	}
+)
+
+// Request checks the request against its validation rules, returning an
+// InvalidArgument status carrying a BadRequest detail with the violated fields.
+// Messages without generated validators are always valid.
+func Request(req interface{}) error {
+	var err error
+	switch v := req.(type) {
+	case allValidator:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	}
+	if err == nil {
+		return nil
+	}
+
+	st := status.New(codes.InvalidArgument, err.Error())
+	if detailed, derr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: violations("", err),
+	}); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// violations flattens nested and aggregated validation errors into field
+// violations, with dotted paths to the offending fields.
+func violations(prefix string, err error) []*errdetails.BadRequest_FieldViolation {
+	if me, ok := err.(multiError); ok {
+		var result []*errdetails.BadRequest_FieldViolation
+		for _, e := range me.AllErrors() {
+			result = append(result, violations(prefix, e)...)
+		}
+		return result
+	}
+
+	fe, ok := err.(fieldError)
+	if !ok {
+		return []*errdetails.BadRequest_FieldViolation{{
+			Field:       prefix,
+			Description: err.Error(),
+		}}
+	}
+
+	field := fe.Field()
+	if prefix != "" {
+		field = prefix + "." + field
+	}
+	// Embedded messages report their own violations as the cause.
+	if cause := fe.Cause(); cause != nil {
+		if _, ok := cause.(fieldError); ok {
+			return violations(field, cause)
+		}
+		if _, ok := cause.(multiError); ok {
+			return violations(field, cause)
+		}
+	}
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       field,
+		Description: fe.Reason(),
+	}}
+}
+
+// UnaryServerInterceptor rejects requests that fail validation before they
+// reach the handler.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := Request(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor validates each message as it is received from the
+// stream, returning the violation from RecvMsg.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ServerStream: ss})
+	}
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return Request(m)
+}
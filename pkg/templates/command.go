@@ -0,0 +1,42 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	output string
+
+	Command = &cobra.Command{
+		Use:   "templates",
+		Short: "Work with the templates from which code and configuration are generated.",
+	}
+
+	dumpCommand = &cobra.Command{
+		Use:   "dump",
+		Short: "Write the default templates out, as a starting point for overriding them.",
+		Run:   dump,
+		Args:  cobra.NoArgs,
+	}
+)
+
+func init() {
+	dumpCommand.Flags().StringVarP(&output, "output", "o", "./templates",
+		"The directory into which to write the templates.")
+
+	Command.AddCommand(dumpCommand)
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func dump(cmd *cobra.Command, args []string) {
+	written, err := Dump(output)
+	if err != nil {
+		log.Fatalf("Error dumping templates: %v", err)
+	}
+	for _, path := range written {
+		log.Printf("Wrote %s", path)
+	}
+	if len(written) == 0 {
+		log.Printf("All of the templates already exist in %s", output)
+	}
+}
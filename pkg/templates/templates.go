@@ -0,0 +1,101 @@
+// Copyright 2019 Matt Moore
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package templates holds the templates from which korpc generates code and
+// configuration, and lets projects override them with their own.
+//
+// Each plugin executes its templates with an options struct, documented
+// alongside the default text.  Since overrides depend on them, those structs
+// are a stable contract: fields may be added, but not removed or changed.
+// Only the registered templates (see Names) may be overridden; the rest of
+// what korpc generates, such as the scaffolded tests, is fixed.
+package templates
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+var defaults = make(map[string]string)
+
+// Register makes the default text of the named template available to Parse
+// and Dump.  Plugins call this from init, so the defaults must parse.
+func Register(name, text string) {
+	if _, ok := defaults[name]; ok {
+		panic(fmt.Sprintf("template %q is already registered", name))
+	}
+	template.Must(template.New(name).Parse(text))
+	defaults[name] = text
+}
+
+// Names returns the names of the registered templates.
+func Names() []string {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse returns the named template, read from the file of that name in dir
+// if there is one, and the default otherwise.  An empty dir means that the
+// project overrides nothing.
+func Parse(dir, name string) (*template.Template, error) {
+	text, ok := defaults[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+	if dir != "" {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		switch {
+		case err == nil:
+			text = string(b)
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %v", name, err)
+	}
+	return t, nil
+}
+
+// Dump writes the default templates into dir, as a starting point for
+// overriding them, and returns the files it wrote.  Existing files are left
+// untouched.
+func Dump(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, name := range Names() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, []byte(defaults[name]), 0644); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}